@@ -0,0 +1,113 @@
+package lti
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// ErrUnauthorized is returned by the Authorizers of the package
+// when a request is not allowed.
+var ErrUnauthorized = errors.New("unauthorized")
+
+// Authorizer decides if a request can access a management or
+// diagnostic handler, like DiagnosticHandler.
+type Authorizer interface {
+	Authorize(r *http.Request) error
+}
+
+// AuthorizerFunc adapts a function to an Authorizer.
+type AuthorizerFunc func(r *http.Request) error
+
+// Authorize calls f(r).
+func (f AuthorizerFunc) Authorize(r *http.Request) error {
+	return f(r)
+}
+
+// Protect returns a handler serving h only to the requests allowed
+// by every Authorizer given, answering 403 to the others.
+func Protect(h http.Handler, auth ...Authorizer) http.Handler {
+	if len(auth) == 0 {
+		return h
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, a := range auth {
+			if err := a.Authorize(r); err != nil {
+				http.Error(w, "Forbidden", http.StatusForbidden)
+				return
+			}
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// APIKeyAuthorizer allows the requests carrying one of Keys in the
+// X-API-Key header, or as an Authorization Bearer token.
+type APIKeyAuthorizer struct {
+	Keys []string
+}
+
+// Authorize checks the key of r.
+func (a *APIKeyAuthorizer) Authorize(r *http.Request) error {
+	key := r.Header.Get("X-API-Key")
+	if h := r.Header.Get("Authorization"); key == "" && strings.HasPrefix(h, "Bearer ") {
+		key = strings.TrimPrefix(h, "Bearer ")
+	}
+	if key == "" {
+		return ErrUnauthorized
+	}
+	for _, k := range a.Keys {
+		if subtle.ConstantTimeCompare([]byte(k), []byte(key)) == 1 {
+			return nil
+		}
+	}
+	return ErrUnauthorized
+}
+
+// IPAllowlist allows the requests coming from its networks. The
+// address is taken from r.RemoteAddr, forwarded headers are not
+// trusted.
+type IPAllowlist struct {
+	nets []*net.IPNet
+}
+
+// NewIPAllowlist returns an IPAllowlist of the networks given in
+// CIDR notation (10.0.0.0/8), or single IPs.
+func NewIPAllowlist(cidrs ...string) (*IPAllowlist, error) {
+	a := &IPAllowlist{}
+	for _, c := range cidrs {
+		if !strings.Contains(c, "/") {
+			if strings.Contains(c, ":") {
+				c += "/128"
+			} else {
+				c += "/32"
+			}
+		}
+		_, n, err := net.ParseCIDR(c)
+		if err != nil {
+			return nil, err
+		}
+		a.nets = append(a.nets, n)
+	}
+	return a, nil
+}
+
+// Authorize checks the address of r.
+func (a *IPAllowlist) Authorize(r *http.Request) error {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return ErrUnauthorized
+	}
+	for _, n := range a.nets {
+		if n.Contains(ip) {
+			return nil
+		}
+	}
+	return ErrUnauthorized
+}
@@ -0,0 +1,73 @@
+package lti
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAPIKeyAuthorizer(t *testing.T) {
+	a := &APIKeyAuthorizer{Keys: []string{"k1", "k2"}}
+	tests := []struct {
+		header, value string
+		ok            bool
+	}{
+		{"X-API-Key", "k1", true},
+		{"Authorization", "Bearer k2", true},
+		{"X-API-Key", "k3", false},
+		{"Authorization", "Basic k1", false},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", "/", nil)
+		if tt.header != "" {
+			r.Header.Set(tt.header, tt.value)
+		}
+		if err := a.Authorize(r); (err == nil) != tt.ok {
+			t.Errorf("%s: %s expected %v, got %v", tt.header, tt.value, tt.ok, err)
+		}
+	}
+}
+
+func TestIPAllowlist(t *testing.T) {
+	a, err := NewIPAllowlist("10.0.0.0/8", "192.168.1.10", "::1")
+	if err != nil {
+		t.Fatalf("Error creating allowlist %s", err)
+	}
+	for addr, ok := range map[string]bool{
+		"10.1.2.3:1234":     true,
+		"192.168.1.10:80":   true,
+		"[::1]:80":          true,
+		"192.168.1.11:80":   false,
+		"8.8.8.8:53":        false,
+		"not an address:80": false,
+	} {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.RemoteAddr = addr
+		if err := a.Authorize(r); (err == nil) != ok {
+			t.Errorf("%s: expected %v, got %v", addr, ok, err)
+		}
+	}
+	if _, err := NewIPAllowlist("10.0.0.0/33"); err == nil {
+		t.Error("Invalid network should fail")
+	}
+}
+
+func TestDiagnosticHandlerAuthorizer(t *testing.T) {
+	h := DiagnosticHandler(NewProvider("asdf", "http://urltest.com/"),
+		&APIKeyAuthorizer{Keys: []string{"key"}})
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("POST", "/", nil))
+	if w.Code != http.StatusForbidden {
+		t.Errorf("Request without key should be forbidden, got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	r := httptest.NewRequest("POST", "/", nil)
+	r.Header.Set("X-API-Key", "key")
+	h.ServeHTTP(w, r)
+	if w.Code == http.StatusForbidden {
+		t.Error("Request with key should be allowed")
+	}
+}
@@ -0,0 +1,48 @@
+package lti
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+
+	"github.com/jordic/lti/oauth"
+)
+
+// defaultMaxBodySize limits the bodies hashed when MaxFormSize is
+// not set, as http.Request.ParseForm limits the forms.
+const defaultMaxBodySize = 10 << 20
+
+// checkBodyHash verifies the oauth_body_hash claimed by a request
+// with a non form body, like the XML of the Outcomes service. The
+// body is read, up to maxSize bytes, and restored, so handlers can
+// still read it. The hash is the one of the signature method,
+// SHA-256 for HMAC-SHA256.
+func checkBodyHash(r *http.Request, method, claimed string, maxSize int64) error {
+	ct, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if claimed == "" || ct == "application/x-www-form-urlencoded" {
+		return nil
+	}
+	if maxSize <= 0 {
+		maxSize = defaultMaxBodySize
+	}
+	if r.Body != nil {
+		r.Body = http.MaxBytesReader(nil, r.Body, maxSize)
+	}
+	err := oauth.CheckBodyHash(r, method, claimed)
+	var he *oauth.BodyHashError
+	var mbe *http.MaxBytesError
+	switch {
+	case errors.As(err, &he):
+		return fmt.Errorf("%w %s, expected %s", ErrInvalidBodyHash, he.Claimed, he.Computed)
+	case errors.As(err, &mbe):
+		return fmt.Errorf("%w, limit is %d bytes", ErrFormTooLarge, mbe.Limit)
+	}
+	return err
+}
+
+// bodyHash returns the oauth_body_hash of body, for a request
+// signed with method.
+func bodyHash(body []byte, method string) string {
+	return oauth.BodyHash(body, oauth.BodyHashAlgorithm(method))
+}
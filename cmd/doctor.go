@@ -0,0 +1,134 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/jordic/lti"
+)
+
+// check is the result of a doctor check.
+type check struct {
+	Name   string `json:"name"`
+	OK     bool   `json:"ok"`
+	Detail string `json:"detail"`
+}
+
+// doctor runs the checks against the launch URL of a tool, using
+// the consumer key and secret given, printing a pass/fail report,
+// as JSON when asJSON is set. When launchFile is given, the launch
+// recorded in it is verified too. It returns false if any check
+// failed.
+func doctor(consumer, secret, launchURL, launchFile string, asJSON bool) bool {
+	var checks []check
+	if launchFile != "" {
+		checks = append(checks, recorded(consumer, secret, launchURL, launchFile))
+	}
+	if launchURL != "" {
+		checks = append(checks, reachable(consumer, secret, launchURL)...)
+	}
+
+	ok := true
+	for _, c := range checks {
+		ok = ok && c.OK
+	}
+	if asJSON {
+		json.NewEncoder(os.Stdout).Encode(struct {
+			OK     bool    `json:"ok"`
+			Checks []check `json:"checks"`
+		}{ok, checks})
+		return ok
+	}
+	for _, c := range checks {
+		status := "PASS"
+		if !c.OK {
+			status = "FAIL"
+		}
+		fmt.Printf("%s  %-20s %s\n", status, c.Name, c.Detail)
+	}
+	return ok
+}
+
+// doctorUsage prints how doctor is run, when it's given nothing to
+// check.
+func doctorUsage() {
+	fmt.Fprintln(os.Stderr, "usage: main doctor -consumer key -secret secret [-url launch_url] [-launch file] [-json]")
+	fmt.Fprintln(os.Stderr, "doctor needs a launch URL or a recorded launch to check")
+	flag.PrintDefaults()
+}
+
+func sampleLaunch(consumer, secret, launchURL string) *lti.Provider {
+	p := lti.NewProvider(secret, launchURL, lti.WithConsumerKey(consumer))
+	p.Add("lti_message_type", lti.LaunchMessageType).
+		Add("lti_version", lti.LTI1p0).
+		Add("resource_link_id", "lti-doctor").
+		Add("user_id", "lti-doctor").
+		Add("roles", "Instructor")
+	return p
+}
+
+// recorded verifies a launch sent by the consumer, recorded in
+// file as its form body, with the key, secret and launch URL given,
+// telling if they match the ones the consumer signs with.
+func recorded(consumer, secret, launchURL, file string) check {
+	c := check{Name: "recorded launch"}
+	if launchURL == "" {
+		c.Detail = "the launch URL the consumer signed is needed, set -url"
+		return c
+	}
+	body, err := ioutil.ReadFile(file)
+	if err != nil {
+		c.Detail = err.Error()
+		return c
+	}
+	v := lti.NewProvider(secret, launchURL, lti.WithConsumerKey(consumer))
+	r, _ := http.NewRequest("POST", launchURL, strings.NewReader(strings.TrimSpace(string(body))))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if res := v.Validate(r); !res.Valid() {
+		c.Detail = fmt.Sprint(res.Errors)
+		return c
+	}
+	c.OK, c.Detail = true, "launch of the consumer verified"
+	return c
+}
+
+// reachable posts a signed launch to the tool, and compares its
+// clock with the local one.
+func reachable(consumer, secret, launchURL string) []check {
+	c := check{Name: "launch URL"}
+	p := sampleLaunch(consumer, secret, launchURL)
+	p.Sign()
+
+	client := &http.Client{
+		Timeout: 10 * time.Second,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	start := time.Now()
+	resp, err := client.PostForm(launchURL, p.Params())
+	if err != nil {
+		c.Detail = err.Error()
+		return []check{c}
+	}
+	resp.Body.Close()
+	c.OK = resp.StatusCode < 400
+	c.Detail = fmt.Sprintf("%s in %s", resp.Status, time.Since(start).Round(time.Millisecond))
+
+	clock := check{Name: "clock skew"}
+	date, err := http.ParseTime(resp.Header.Get("Date"))
+	if err != nil {
+		clock.Detail = "no Date header in the response"
+		return []check{c, clock}
+	}
+	skew := time.Since(date).Round(time.Second)
+	clock.OK = skew < time.Minute && skew > -time.Minute
+	clock.Detail = fmt.Sprintf("%s from local time", skew)
+	return []check{c, clock}
+}
@@ -1,10 +1,14 @@
 package main
 
 import (
+	"encoding/json"
 	"flag"
 	"fmt"
 	"log"
 	"net/http"
+	"net/url"
+	"os"
+	"time"
 
 	"github.com/jordic/lti"
 )
@@ -12,13 +16,47 @@ import (
 // This package allows to test the lib, acting as a webserver, and
 // responding to a / endpoint... that should receive POST requests..
 
+// Running it as
+//
+//  main doctor -consumer key -secret secret -url https://tool/launch
+//
+// checks the tool at url instead, printing a pass/fail report. With
+// -launch, a launch recorded from the consumer, its form body saved
+// to a file, is verified with the key, secret and url given.
+//
+// With -json, the doctor report, and every launch received by the
+// server, are written to stdout as JSON, for CI pipelines.
+
 var (
 	secret      = flag.String("secret", "", "Default secret for use during testing")
 	consumer    = flag.String("consumer", "", "Def consumer")
 	httpAddress = flag.String("http", "localhost:5001", "Listen to")
+	launchURL   = flag.String("url", "", "Launch URL of the tool checked by doctor")
+	jsonReport  = flag.Bool("json", false, "Write the reports as JSON to stdout")
+	launchFile  = flag.String("launch", "", "File with the form body of a recorded launch verified by doctor")
 )
 
+// launchReport is written for every launch received with -json.
+type launchReport struct {
+	Time        time.Time  `json:"time"`
+	ConsumerKey string     `json:"consumer_key"`
+	Valid       bool       `json:"valid"`
+	Errors      []string   `json:"errors,omitempty"`
+	Params      url.Values `json:"params"`
+}
+
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "doctor" {
+		flag.CommandLine.Parse(os.Args[2:])
+		if *launchURL == "" && *launchFile == "" {
+			doctorUsage()
+			os.Exit(2)
+		}
+		if !doctor(*consumer, *secret, *launchURL, *launchFile, *jsonReport) {
+			os.Exit(1)
+		}
+		return
+	}
 	flag.Parse()
 
 	http.HandleFunc("/", ltiHandler)
@@ -38,6 +76,18 @@ func ltiHandler(w http.ResponseWriter, r *http.Request) {
 	p.ConsumerKey = *consumer
 
 	ok, err := p.IsValid(r)
+	if *jsonReport {
+		rep := launchReport{
+			Time:        time.Now(),
+			ConsumerKey: p.Get("oauth_consumer_key"),
+			Valid:       ok,
+			Params:      p.Redacted(),
+		}
+		if err != nil {
+			rep.Errors = []string{err.Error()}
+		}
+		json.NewEncoder(os.Stdout).Encode(rep)
+	}
 	if ok == false {
 		fmt.Fprintf(w, "Invalid request...")
 	}
@@ -49,8 +99,7 @@ func ltiHandler(w http.ResponseWriter, r *http.Request) {
 	if ok == true {
 
 		fmt.Fprintf(w, "Request Ok<br/>")
-		data := fmt.Sprintf("User %s", p.Get("user_id"))
-		fmt.Fprintf(w, data)
+		fmt.Fprintf(w, "User %s", p.Get("user_id"))
 
 	}
 
@@ -0,0 +1,44 @@
+package lti
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/jordic/lti/oauth"
+)
+
+// TestCompatV01 uses the API as released in v0.1, so changes
+// breaking existing users fail to build or pass.
+func TestCompatV01(t *testing.T) {
+	var p *Provider = NewProvider("secret", "http://urltest.com/")
+	p.ConsumerKey = "12345"
+	p.Method = "POST"
+	p.Secret = "secret"
+	p.URL = "http://urltest.com/"
+	p.SetSigner(oauth.GetHMACSigner("secret", ""))
+
+	var chained *Provider = p.Add("resource_link_id", "1").SetParams(p.Params())
+	var sig string
+	var err error
+	sig, err = chained.Sign()
+	if err != nil || sig == "" || p.Get("oauth_signature") != sig {
+		t.Fatalf("Sign should work as in v0.1, got %s %v", sig, err)
+	}
+	if p.Empty("resource_link_id") || p.HasRole("Instructor") {
+		t.Error("Empty and HasRole should work as in v0.1")
+	}
+
+	sig, err = Sign(p.Params(), p.URL, p.Method, p.Signer)
+	if err != nil || sig != p.Get("oauth_signature") {
+		t.Errorf("Package Sign should work as in v0.1, got %s %v", sig, err)
+	}
+
+	var ok bool
+	ok, err = NewProvider("secret", "http://urltest.com/").IsValid(&http.Request{Method: "POST", Form: p.Params()})
+	if ok {
+		t.Error("IsValid should reject a wrong consumer key as in v0.1")
+	}
+	if SigHMAC != "HMAC-SHA1" {
+		t.Error("SigHMAC changed")
+	}
+}
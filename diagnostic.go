@@ -0,0 +1,152 @@
+package lti
+
+import (
+	"errors"
+	"html/template"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// DiagnosticHandler returns an http.Handler that verifies a launch
+// with v, like a Provider, and renders a report of what was
+// received. It is meant to be mounted on a tool, so LMS admins can
+// point a link to it as a first "connection test" when configuring
+// the tool:
+//
+//  http.Handle("/lti/test", lti.DiagnosticHandler(p, auth))
+//
+// Only the requests allowed by every Authorizer given get the
+// report; without Authorizers every request is answered 403, as the
+// report is not meant to be public. Failed launches are reported
+// only by the category of the failure, never with the signature
+// computed for them.
+func DiagnosticHandler(v LaunchVerifier, auth ...Authorizer) http.Handler {
+	if len(auth) == 0 {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+		})
+	}
+	return Protect(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p, err := v.Verify(r)
+		if p == nil {
+			p = &Provider{}
+		}
+		ok := err == nil
+		rep := newReport(p, ok, err)
+
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		if !ok {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+		diagnosticTemplate.Execute(w, rep)
+	}), auth...)
+}
+
+// report holds the results shown by DiagnosticHandler.
+type report struct {
+	Valid        bool
+	Error        string
+	Violations   []error
+	ConsumerKey  string
+	Product      string
+	Version      string
+	Instance     string
+	Roles        []Role
+	Capabilities []string
+	ClockSkew    string
+	Privacy      string
+}
+
+func newReport(p *Provider, ok bool, err error) report {
+	rep := report{
+		Valid:       ok,
+		ConsumerKey: p.Get("oauth_consumer_key"),
+		Product:     p.Get("tool_consumer_info_product_family_code"),
+		Version:     p.Get("tool_consumer_info_version"),
+		Instance:    p.Get("tool_consumer_instance_guid"),
+		Roles:       ParseRoles(p.Get("roles")),
+		Privacy:     privacyLevel(p),
+	}
+	if err != nil {
+		rep.Error = failureCategory(err)
+	}
+	if ok {
+		rep.Violations = p.ValidateLaunch()
+	}
+	if !p.Empty("lis_outcome_service_url") && !p.Empty("lis_result_sourcedid") {
+		rep.Capabilities = append(rep.Capabilities, "Basic Outcomes (grade passback)")
+	}
+	if !p.Empty("launch_presentation_return_url") {
+		rep.Capabilities = append(rep.Capabilities, "Return URL")
+	}
+	if !p.Empty("ext_content_return_types") {
+		rep.Capabilities = append(rep.Capabilities, "Content selection")
+	}
+	if ts, err := strconv.ParseInt(p.Get("oauth_timestamp"), 10, 64); err == nil {
+		rep.ClockSkew = p.now().Sub(time.Unix(ts, 0)).Round(time.Second).String()
+	}
+	return rep
+}
+
+// failureCategories are the failures shown by DiagnosticHandler, in
+// the order they are checked.
+var failureCategories = []error{
+	ErrOverloaded,
+	ErrFormTooLarge,
+	ErrGETLaunch,
+	ErrInvalidConsumerKey,
+	ErrInvalidSignatureMethod,
+	ErrUnsupportedOAuthVersion,
+	ErrUnexpectedOAuthParam,
+	ErrExpiredTimestamp,
+	ErrReplayedNonce,
+	ErrInvalidBodyHash,
+	ErrInvalidSignature,
+}
+
+// failureCategory returns the message of the sentinel matching err,
+// without the details wrapped with it.
+func failureCategory(err error) string {
+	for _, c := range failureCategories {
+		if errors.Is(err, c) {
+			return c.Error()
+		}
+	}
+	return invalidLaunch
+}
+
+// privacyLevel describes which personal data the consumer sends.
+func privacyLevel(p *Provider) string {
+	name := !p.Empty("lis_person_name_full") || !p.Empty("lis_person_name_given")
+	email := !p.Empty("lis_person_contact_email_primary")
+	switch {
+	case name && email:
+		return "public (name and email)"
+	case name:
+		return "name only"
+	case email:
+		return "email only"
+	}
+	return "anonymous"
+}
+
+var diagnosticTemplate = template.Must(template.New("diagnostic").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>LTI connection test</title></head>
+<body>
+{{if .Valid}}<h1>Launch OK</h1>{{else}}<h1>Launch failed</h1>
+<p>{{.Error}}</p>{{end}}
+{{with .Violations}}<h2>Launch parameter problems</h2>
+<ul>{{range .}}<li>{{.}}</li>{{end}}</ul>{{end}}
+<table>
+<tr><th>Consumer key</th><td>{{.ConsumerKey}}</td></tr>
+<tr><th>Consumer</th><td>{{.Product}} {{.Version}} {{.Instance}}</td></tr>
+<tr><th>Roles</th><td>{{range .Roles}}{{.}} {{end}}</td></tr>
+<tr><th>Capabilities</th><td>{{range .Capabilities}}{{.}}<br>{{end}}</td></tr>
+<tr><th>Clock skew</th><td>{{.ClockSkew}}</td></tr>
+<tr><th>Privacy</th><td>{{.Privacy}}</td></tr>
+</table>
+</body>
+</html>
+`))
@@ -0,0 +1,81 @@
+package lti
+
+import (
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDiagnosticHandler(t *testing.T) {
+	p := NewProvider("asdf", "http://urltest.com/")
+	p.ConsumerKey = "12345"
+
+	c := NewProvider("asdf", "http://urltest.com/")
+	c.ConsumerKey = "12345"
+	c.SetParams(url.Values{})
+	c.Add("lti_message_type", LaunchMessageType).
+		Add("lti_version", "LTI-1p0").
+		Add("resource_link_id", "1").
+		Add("roles", "Instructor").
+		Add("lis_person_name_full", "Jane Q. Public").
+		Add("tool_consumer_info_product_family_code", "moodle")
+	c.Sign()
+
+	h := DiagnosticHandler(p, &APIKeyAuthorizer{Keys: []string{"k"}})
+	w := httptest.NewRecorder()
+	r, _ := http.NewRequest("POST", "http://urltest.com/", strings.NewReader(c.Params().Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	r.Header.Set("X-API-Key", "k")
+	h.ServeHTTP(w, r)
+
+	body := w.Body.String()
+	if w.Code != http.StatusOK || !strings.Contains(body, "Launch OK") {
+		t.Errorf("Launch should be valid, got %d %s", w.Code, body)
+	}
+	for _, s := range []string{"moodle", "urn:lti:role:ims/lis/Instructor", "name only"} {
+		if !strings.Contains(body, s) {
+			t.Errorf("Report should contain %s", s)
+		}
+	}
+	if !p.Empty("roles") {
+		t.Error("Template provider should not be modified")
+	}
+
+	c.Add("roles", "Learner")
+	w = httptest.NewRecorder()
+	r, _ = http.NewRequest("POST", "http://urltest.com/", strings.NewReader(c.Params().Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	r.Header.Set("X-API-Key", "k")
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusUnauthorized || !strings.Contains(w.Body.String(), "invalid signature") {
+		t.Errorf("Tampered launch should fail, got %d %s", w.Code, w.Body.String())
+	}
+	c.Sign()
+	if sig := c.Get("oauth_signature"); strings.Contains(w.Body.String(), sig) ||
+		strings.Contains(w.Body.String(), template.HTMLEscapeString(sig)) {
+		t.Error("Report should not contain the expected signature")
+	}
+}
+
+func TestDiagnosticHandlerNoAuthorizer(t *testing.T) {
+	h := DiagnosticHandler(NewProvider("asdf", "http://urltest.com/"))
+	w := httptest.NewRecorder()
+	r, _ := http.NewRequest("POST", "http://urltest.com/", nil)
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusForbidden {
+		t.Errorf("Handler without Authorizers should refuse to serve, got %d", w.Code)
+	}
+}
+
+func TestDiagnosticClockSkew(t *testing.T) {
+	p := NewProvider("asdf", "http://urltest.com/")
+	p.Clock = fixedClock(time.Unix(1348093650, 0))
+	p.SetParams(url.Values{"oauth_timestamp": {"1348093590"}})
+	if rep := newReport(p, false, ErrInvalidSignature); rep.ClockSkew != "1m0s" {
+		t.Errorf("Clock skew should use the provider clock, got %s", rep.ClockSkew)
+	}
+}
@@ -0,0 +1,170 @@
+package lti
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"io"
+	"net/url"
+)
+
+// FieldEncrypter encrypts the values of the personal data fields
+// of a launch before it's stored, and decrypts them when read.
+// The field name is given so implementations can use it as
+// additional data, or pick a key by field.
+type FieldEncrypter interface {
+	Encrypt(field, value string) (string, error)
+	Decrypt(field, value string) (string, error)
+}
+
+// EncryptParams returns a copy of v with the values of the fields
+// masked by policy encrypted with e. EncryptedLaunchStore applies it
+// to every launch saved.
+func EncryptParams(v url.Values, e FieldEncrypter, policy RedactionPolicy) (url.Values, error) {
+	return mapFields(v, policy, e.Encrypt)
+}
+
+// DecryptParams returns a copy of v with the values of the fields
+// masked by policy decrypted with e.
+func DecryptParams(v url.Values, e FieldEncrypter, policy RedactionPolicy) (url.Values, error) {
+	return mapFields(v, policy, e.Decrypt)
+}
+
+func mapFields(v url.Values, policy RedactionPolicy, f func(field, value string) (string, error)) (url.Values, error) {
+	res := url.Values{}
+	for k, vs := range v {
+		res[k] = append([]string(nil), vs...)
+		if !policy.masks(k) {
+			continue
+		}
+		for i, val := range vs {
+			s, err := f(k, val)
+			if err != nil {
+				return nil, err
+			}
+			res[k][i] = s
+		}
+	}
+	return res, nil
+}
+
+// EncryptedLaunchStore is a LaunchStore that encrypts the launches
+// before saving them in Store, and decrypts them once loaded, so
+// the store only holds the fields masked by Policy encrypted:
+//
+//  e, err := lti.NewAESEncrypter(key)
+//  ...
+//  store := lti.NewEncryptedLaunchStore(sqlStore, e)
+type EncryptedLaunchStore struct {
+	Store     LaunchStore
+	Encrypter FieldEncrypter
+	// Policy sets the fields encrypted, the params and the fields of
+	// Launch holding them.
+	Policy RedactionPolicy
+}
+
+// NewEncryptedLaunchStore returns an EncryptedLaunchStore saving
+// the launches in s encrypted with e, the fields masked by
+// DefaultRedactionPolicy.
+func NewEncryptedLaunchStore(s LaunchStore, e FieldEncrypter) *EncryptedLaunchStore {
+	return &EncryptedLaunchStore{Store: s, Encrypter: e, Policy: DefaultRedactionPolicy}
+}
+
+// SaveLaunch saves l in Store, encrypted. l is not modified.
+func (s *EncryptedLaunchStore) SaveLaunch(ctx context.Context, l *Launch) error {
+	enc, err := s.mapLaunch(l, s.Encrypter.Encrypt)
+	if err != nil {
+		return err
+	}
+	return s.Store.SaveLaunch(ctx, enc)
+}
+
+// LoadLaunch loads the launch with the id given from Store,
+// decrypted.
+func (s *EncryptedLaunchStore) LoadLaunch(ctx context.Context, id string) (*Launch, error) {
+	l, err := s.Store.LoadLaunch(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return s.mapLaunch(l, s.Encrypter.Decrypt)
+}
+
+// mapLaunch returns a copy of l with f applied to the fields masked
+// by Policy.
+func (s *EncryptedLaunchStore) mapLaunch(l *Launch, f func(field, value string) (string, error)) (*Launch, error) {
+	c := *l
+	var err error
+	if c.Params, err = mapFields(l.Params, s.Policy, f); err != nil {
+		return nil, err
+	}
+	for _, lf := range []struct {
+		param string
+		value *string
+	}{
+		{"user_id", &c.UserID},
+		{"roles", &c.Roles},
+		{"context_id", &c.ContextID},
+		{"resource_link_id", &c.ResourceLinkID},
+		{"lis_outcome_service_url", &c.OutcomeServiceURL},
+		{"lis_result_sourcedid", &c.ResultSourcedID},
+	} {
+		if *lf.value == "" || !s.Policy.masks(lf.param) {
+			continue
+		}
+		if *lf.value, err = f(lf.param, *lf.value); err != nil {
+			return nil, err
+		}
+	}
+	return &c, nil
+}
+
+// AESEncrypter is a FieldEncrypter using AES-GCM, with the field
+// name as additional data, so a value can't be moved to another
+// field. Values are encoded as base64.
+type AESEncrypter struct {
+	aead cipher.AEAD
+}
+
+// NewAESEncrypter returns an AESEncrypter using key, that must be
+// 16, 24 or 32 bytes long.
+func NewAESEncrypter(key []byte) (*AESEncrypter, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &AESEncrypter{aead: aead}, nil
+}
+
+// Encrypt encrypts value.
+func (e *AESEncrypter) Encrypt(field, value string) (string, error) {
+	nonce := make([]byte, e.aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+	b := e.aead.Seal(nonce, nonce, []byte(value), []byte(field))
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// Decrypt decrypts a value encrypted by Encrypt.
+func (e *AESEncrypter) Decrypt(field, value string) (string, error) {
+	b, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return "", err
+	}
+	n := e.aead.NonceSize()
+	if len(b) < n {
+		return "", errors.New("encrypted value too short")
+	}
+	plain, err := e.aead.Open(nil, b[:n], b[n:], []byte(field))
+	if err != nil {
+		return "", err
+	}
+	return string(plain), nil
+}
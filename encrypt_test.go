@@ -0,0 +1,86 @@
+package lti
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestEncryptParams(t *testing.T) {
+	e, err := NewAESEncrypter(bytes.Repeat([]byte("k"), 32))
+	if err != nil {
+		t.Fatalf("Error creating encrypter %s", err)
+	}
+	v := GenerateForm()
+
+	enc, err := EncryptParams(v, e, DefaultRedactionPolicy)
+	if err != nil {
+		t.Fatalf("Error encrypting %s", err)
+	}
+	if enc.Get("lis_person_name_full") == v.Get("lis_person_name_full") {
+		t.Error("Personal data should be encrypted")
+	}
+	if enc.Get("context_id") != v.Get("context_id") {
+		t.Error("Other params should be kept")
+	}
+
+	dec, err := DecryptParams(enc, e, DefaultRedactionPolicy)
+	if err != nil {
+		t.Fatalf("Error decrypting %s", err)
+	}
+	if dec.Encode() != v.Encode() {
+		t.Errorf("Decrypted params don't match\n%s\n%s", dec.Encode(), v.Encode())
+	}
+
+	moved := enc.Get("lis_person_name_full")
+	if _, err := e.Decrypt("lis_person_name_given", moved); err == nil {
+		t.Error("A value moved to another field should not decrypt")
+	}
+}
+
+func TestEncryptedLaunchStore(t *testing.T) {
+	ctx := context.Background()
+	e, err := NewAESEncrypter(bytes.Repeat([]byte("k"), 32))
+	if err != nil {
+		t.Fatalf("Error creating encrypter %s", err)
+	}
+	mem := NewMemoryLaunchStore()
+	s := NewEncryptedLaunchStore(mem, e)
+
+	p := NewProvider("secret", "http://localhost")
+	p.SetParams(GenerateForm())
+	l := NewLaunch(p)
+	if err := s.SaveLaunch(ctx, l); err != nil {
+		t.Fatalf("Error saving launch %s", err)
+	}
+	if l.ResultSourcedID != "feb-123-456-2929::28883" {
+		t.Error("The launch saved should not be modified")
+	}
+
+	stored, _ := mem.LoadLaunch(ctx, l.ID)
+	for k, vs := range l.Params {
+		if DefaultRedactionPolicy.masks(k) && stored.Params.Get(k) == vs[0] {
+			t.Errorf("%s should be stored encrypted", k)
+		}
+	}
+	if stored.ResultSourcedID == l.ResultSourcedID ||
+		strings.Contains(stored.Params.Encode(), "Jane") {
+		t.Errorf("The store should only hold ciphertext %#v", stored)
+	}
+	if stored.ContextID != l.ContextID {
+		t.Error("Other fields should be stored as they are")
+	}
+
+	got, err := s.LoadLaunch(ctx, l.ID)
+	if err != nil {
+		t.Fatalf("Error loading launch %s", err)
+	}
+	if !reflect.DeepEqual(got, l) {
+		t.Errorf("Launch should be decrypted\n%#v\n%#v", got, l)
+	}
+	if _, err := s.LoadLaunch(ctx, "missing"); err != ErrLaunchNotFound {
+		t.Errorf("Expected ErrLaunchNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,27 @@
+package lti
+
+import "errors"
+
+// Errors returned by IsValid, wrapped with the details of the
+// failure.
+var (
+	ErrInvalidConsumerKey      = errors.New("invalid consumer key")
+	ErrInvalidSignatureMethod  = errors.New("wrong signature method")
+	ErrUnsupportedOAuthVersion = errors.New("unsupported oauth_version")
+	ErrInvalidSignature        = errors.New("invalid signature")
+	ErrInvalidBodyHash         = errors.New("invalid oauth_body_hash")
+	ErrExpiredTimestamp        = errors.New("expired timestamp")
+	ErrReplayedNonce           = errors.New("replayed nonce")
+	ErrFormTooLarge            = errors.New("request body too large")
+	ErrUnexpectedOAuthParam    = errors.New("unexpected oauth param")
+)
+
+// ErrUnsupportedLTIVersion is reported by ValidateLaunch for the
+// launches with an lti_version not supported.
+var ErrUnsupportedLTIVersion = errors.New("unsupported lti_version")
+
+// ErrGETLaunch is returned by IsValid when a launch with a valid
+// signature is received as a GET request, and so the consumer
+// needs to be configured to send its launches as POST.
+var ErrGETLaunch = errors.New("launch received as GET with a valid signature: " +
+	"the consumer should be configured to POST launches")
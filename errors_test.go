@@ -0,0 +1,164 @@
+package lti
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/jordic/lti/oauth"
+)
+
+func TestIsValidErrors(t *testing.T) {
+	p := NewProvider("asdf", "http://urltest.com/",
+		WithConsumerKey("12345"),
+		WithNonceStore(NewMemoryNonceStore(10*time.Minute)))
+
+	r := signedRequest(t, "http://urltest.com/")
+	if ok, err := p.IsValid(r); !ok {
+		t.Fatalf("Request should be valid, got %s", err)
+	}
+	if _, err := p.IsValid(r); !errors.Is(err, ErrReplayedNonce) {
+		t.Errorf("Replayed request should fail with ErrReplayedNonce, got %v", err)
+	}
+
+	r = signedRequest(t, "http://urltest.com/")
+	r.Form.Set("oauth_consumer_key", "other")
+	if _, err := p.IsValid(r); !errors.Is(err, ErrInvalidConsumerKey) {
+		t.Errorf("Expected ErrInvalidConsumerKey, got %v", err)
+	}
+
+	r = signedRequest(t, "http://urltest.com/")
+	r.Form.Set("resource_link_id", "tampered")
+	if _, err := p.IsValid(r); !errors.Is(err, ErrInvalidSignature) || err.Error() != ErrInvalidSignature.Error() {
+		t.Errorf("Expected bare ErrInvalidSignature, got %v", err)
+	}
+
+	c := NewProvider("asdf", "http://urltest.com/", WithConsumerKey("12345"))
+	c.Add("oauth_timestamp", strconv.FormatInt(time.Now().Add(-time.Hour).Unix(), 10))
+	c.Sign()
+	r = &http.Request{Method: "POST", Form: c.Params()}
+	if _, err := p.IsValid(r); !errors.Is(err, ErrExpiredTimestamp) {
+		t.Errorf("Expected ErrExpiredTimestamp, got %v", err)
+	}
+}
+
+func TestMemoryNonceStore(t *testing.T) {
+	s := NewMemoryNonceStore(time.Minute)
+	now := time.Now()
+	if seen, _ := s.Seen("key", "n1", now); seen {
+		t.Error("Nonce should not be seen")
+	}
+	if seen, _ := s.Seen("key", "n1", now); !seen {
+		t.Error("Nonce should be seen")
+	}
+	if seen, _ := s.Seen("other", "n1", now); seen {
+		t.Error("Nonces should be kept by consumer key")
+	}
+
+	s.nonces["key\x00old"] = now.Add(-2 * time.Minute)
+	s.lastSweep = now.Add(-2 * time.Minute)
+	s.Seen("key", "n2", now)
+	if _, ok := s.nonces["key\x00old"]; ok {
+		t.Error("Expired nonces should be evicted")
+	}
+	if st := s.Stats(); st.Entries != 3 || st.Evicted != 1 {
+		t.Errorf("Unexpected stats %+v", st)
+	}
+	var _ NonceStatsReporter = s
+}
+
+func TestMemoryNonceStoreClock(t *testing.T) {
+	now := time.Unix(1348093590, 0)
+	s := NewMemoryNonceStore(time.Minute)
+	s.Clock = oauth.ClockFunc(func() time.Time { return now })
+	s.Seen("key", "n1", now)
+	now = now.Add(2 * time.Minute)
+	s.Seen("key", "n2", now)
+	if st := s.Stats(); st.Entries != 1 || st.Evicted != 1 {
+		t.Errorf("Nonces should be swept on the time of the Clock, got %+v", st)
+	}
+}
+
+func TestOAuthVersion(t *testing.T) {
+	tests := []struct {
+		version string
+		allow   bool
+		valid   bool
+	}{
+		{"", false, true},
+		{"1.0", false, true},
+		{"1.0a", false, false},
+		{"1.0a", true, true},
+		{"2.0", true, false},
+	}
+	for _, tt := range tests {
+		c := NewProvider("asdf", "http://urltest.com/", WithConsumerKey("12345"))
+		c.Sign()
+		v := c.Params()
+		v.Del("oauth_version")
+		if tt.version != "" {
+			v.Set("oauth_version", tt.version)
+		}
+		sig, _ := Sign(v, c.URL, "POST", c.Signer)
+		v.Set("oauth_signature", sig)
+
+		p := NewProvider("asdf", "http://urltest.com/", WithConsumerKey("12345"))
+		p.AllowOAuth10a = tt.allow
+		ok, err := p.IsValid(&http.Request{Method: "POST", Form: v})
+		if ok != tt.valid {
+			t.Errorf("Version %q allowing 1.0a %v: expected %v, got %v (%v)", tt.version, tt.allow, tt.valid, ok, err)
+		}
+		if !ok && !errors.Is(err, ErrUnsupportedOAuthVersion) {
+			t.Errorf("Expected ErrUnsupportedOAuthVersion, got %v", err)
+		}
+	}
+}
+
+func TestMaxFormSize(t *testing.T) {
+	p := NewProvider("asdf", "http://urltest.com/", WithConsumerKey("12345"))
+	r := launchRequest(t, "http://urltest.com/", "Learner")
+	p.MaxFormSize = r.ContentLength - 1
+	if _, err := p.IsValid(r); !errors.Is(err, ErrFormTooLarge) {
+		t.Errorf("Expected ErrFormTooLarge, got %v", err)
+	}
+
+	r = launchRequest(t, "http://urltest.com/", "Learner")
+	p.MaxFormSize = r.ContentLength
+	if ok, err := p.IsValid(r); !ok {
+		t.Errorf("Request within the limit should be valid, got %s", err)
+	}
+}
+
+func TestClockSkew(t *testing.T) {
+	p := NewProvider("asdf", "http://urltest.com/", WithConsumerKey("12345"))
+	launch := func(age time.Duration) *http.Request {
+		c := NewProvider("asdf", "http://urltest.com/", WithConsumerKey("12345"))
+		c.Add("resource_link_id", "1").
+			Add("oauth_timestamp", strconv.FormatInt(time.Now().Add(-age).Unix(), 10))
+		c.Sign()
+		return &http.Request{Method: "POST", Form: c.Params()}
+	}
+
+	if ok, err := p.IsValid(launch(time.Hour)); !ok {
+		t.Errorf("Timestamp should not be checked by default, got %s", err)
+	}
+
+	p.Nonces = NewMemoryNonceStore(DefaultClockSkew)
+	if ok, err := p.IsValid(launch(4 * time.Minute)); !ok {
+		t.Errorf("Timestamp within DefaultClockSkew should be valid, got %s", err)
+	}
+	if _, err := p.IsValid(launch(-6 * time.Minute)); !errors.Is(err, ErrExpiredTimestamp) {
+		t.Errorf("Timestamp beyond DefaultClockSkew should fail, got %v", err)
+	}
+
+	p.ClockSkew = 10 * time.Minute
+	if ok, err := p.IsValid(launch(-6 * time.Minute)); !ok {
+		t.Errorf("Timestamp within ClockSkew should be valid, got %s", err)
+	}
+	p.ClockSkew = time.Minute
+	if _, err := p.IsValid(launch(2 * time.Minute)); !errors.Is(err, ErrExpiredTimestamp) {
+		t.Errorf("Timestamp beyond ClockSkew should fail, got %v", err)
+	}
+}
@@ -0,0 +1,87 @@
+package lti
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// ExtraOAuthPolicy tells IsValid what to do with the oauth_ params
+// of a launch not defined by OAuth 1.0 for it, like the
+// oauth_body_hash some consumers add to form launches.
+type ExtraOAuthPolicy int
+
+const (
+	// ExtraOAuthSign signs them as any other param, as the spec
+	// says.
+	ExtraOAuthSign ExtraOAuthPolicy = iota
+	// ExtraOAuthIgnore leaves them out of the signature.
+	ExtraOAuthIgnore
+	// ExtraOAuthReject rejects the launches with them.
+	ExtraOAuthReject
+)
+
+// oauthParams are the oauth_ params expected on a launch.
+var oauthParams = map[string]bool{
+	"oauth_consumer_key":     true,
+	"oauth_signature_method": true,
+	"oauth_signature":        true,
+	"oauth_timestamp":        true,
+	"oauth_nonce":            true,
+	"oauth_version":          true,
+	"oauth_callback":         true,
+	"oauth_token":            true,
+}
+
+// extraOAuthParams returns the names of the unexpected oauth_ params
+// of form, sorted. oauth_body_hash is only expected on requests
+// with a non form body.
+func extraOAuthParams(r *http.Request, form url.Values) []string {
+	ct, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	var extra []string
+	for k := range form {
+		if !strings.HasPrefix(k, "oauth_") || oauthParams[k] {
+			continue
+		}
+		if k == "oauth_body_hash" && ct != "application/x-www-form-urlencoded" {
+			continue
+		}
+		extra = append(extra, k)
+	}
+	sort.Strings(extra)
+	return extra
+}
+
+// extraOAuthPolicy returns the policy for the consumer key.
+func (p *Provider) extraOAuthPolicy(consumerKey string) ExtraOAuthPolicy {
+	if pol, ok := p.ExtraOAuthByConsumer[consumerKey]; ok {
+		return pol
+	}
+	return p.ExtraOAuth
+}
+
+// checkExtraOAuth applies the policy of the consumer to the extra
+// oauth_ params of form, returning the params to be signed.
+func (p *Provider) checkExtraOAuth(r *http.Request, consumerKey string, form url.Values) (url.Values, error) {
+	extra := extraOAuthParams(r, form)
+	if len(extra) == 0 {
+		return form, nil
+	}
+	switch p.extraOAuthPolicy(consumerKey) {
+	case ExtraOAuthReject:
+		return form, fmt.Errorf("%w %s", ErrUnexpectedOAuthParam, strings.Join(extra, ", "))
+	case ExtraOAuthIgnore:
+		signed := make(url.Values, len(form))
+		for k, vs := range form {
+			signed[k] = vs
+		}
+		for _, k := range extra {
+			delete(signed, k)
+		}
+		return signed, nil
+	}
+	return form, nil
+}
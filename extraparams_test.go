@@ -0,0 +1,41 @@
+package lti
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestExtraOAuthPolicy(t *testing.T) {
+	// a consumer signing a form launch without the oauth_body_hash
+	// it sends.
+	launch := func() *http.Request {
+		c := NewProvider("asdf", "http://urltest.com/", WithConsumerKey("12345"))
+		c.Add("resource_link_id", "1")
+		c.Sign()
+		c.Add("oauth_body_hash", "2jmj7l5rSw0yVb/vlWAYkK/YBwk=")
+		r, _ := http.NewRequest("POST", "http://urltest.com/", strings.NewReader(c.Params().Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		return r
+	}
+
+	p := NewProvider("asdf", "http://urltest.com/", WithConsumerKey("12345"))
+	if _, err := p.IsValid(launch()); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("Signing extra params should fail, got %v", err)
+	}
+
+	p.ExtraOAuth = ExtraOAuthIgnore
+	if ok, err := p.IsValid(launch()); !ok {
+		t.Errorf("Ignoring extra params should be valid, got %s", err)
+	}
+	if p.Get("oauth_body_hash") == "" {
+		t.Error("Ignored params should be kept in the params")
+	}
+
+	p.ExtraOAuthByConsumer = map[string]ExtraOAuthPolicy{"12345": ExtraOAuthReject}
+	_, err := p.IsValid(launch())
+	if !errors.Is(err, ErrUnexpectedOAuthParam) || !strings.Contains(err.Error(), "oauth_body_hash") {
+		t.Errorf("Expected ErrUnexpectedOAuthParam for the consumer, got %v", err)
+	}
+}
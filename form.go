@@ -0,0 +1,76 @@
+package lti
+
+import (
+	"bytes"
+	"html/template"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// AutoSubmitHTML returns an HTML page with a form posting the params
+// of the provider to its URL, submitted as soon as it's loaded. It's
+// the way LTI launches are delivered through the browser:
+//
+//  p.Sign()
+//  html, err := p.AutoSubmitHTML()
+//  ...
+//  w.Write([]byte(html))
+//
+// The params should be signed before rendering them. They are
+// rendered sorted by name, so the same params always render the
+// same page.
+func (p *Provider) AutoSubmitHTML() (string, error) {
+	var b bytes.Buffer
+	err := autoSubmitTemplate.Execute(&b, struct {
+		URL    string
+		Params url.Values
+	}{p.URL, p.Params()})
+	return b.String(), err
+}
+
+// FuncMap holds the template functions of the package, to render
+// launch forms from the templates of an application:
+//
+//  t := template.New("launch").Funcs(lti.FuncMap)
+//  ...
+//  <form action="{{.URL}}" method="post">
+//  {{ltiHiddenInputs .Params}}
+//  </form>
+var FuncMap = template.FuncMap{
+	"ltiHiddenInputs": HiddenInputs,
+}
+
+// HiddenInputs renders the params as hidden input fields, one by
+// value, escaping names and values. The fields are sorted by name,
+// keeping the order of the values of each one.
+func HiddenInputs(params url.Values) template.HTML {
+	keys := make([]string, 0, len(params))
+	for k := range params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	for _, k := range keys {
+		for _, v := range params[k] {
+			b.WriteString(`<input type="hidden" name="`)
+			b.WriteString(template.HTMLEscapeString(k))
+			b.WriteString(`" value="`)
+			b.WriteString(template.HTMLEscapeString(v))
+			b.WriteString("\">\n")
+		}
+	}
+	return template.HTML(b.String())
+}
+
+var autoSubmitTemplate = template.Must(template.New("autosubmit").Funcs(FuncMap).Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>Launching...</title></head>
+<body onload="document.forms[0].submit()">
+<form action="{{.URL}}" method="post" enctype="application/x-www-form-urlencoded">
+{{ltiHiddenInputs .Params}}<noscript><input type="submit" value="Continue"></noscript>
+</form>
+</body>
+</html>
+`))
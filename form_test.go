@@ -0,0 +1,51 @@
+package lti
+
+import (
+	"html/template"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestAutoSubmitHTML(t *testing.T) {
+	p := NewProvider("asdf", "http://urltest.com/launch?a=1&b=2", WithConsumerKey("12345"))
+	p.Add("resource_link_id", "1").
+		Add("context_title", `"Design" <of> & Environments`)
+	p.Sign()
+
+	html, err := p.AutoSubmitHTML()
+	if err != nil {
+		t.Fatalf("Error rendering form %s", err)
+	}
+	for _, s := range []string{
+		`action="http://urltest.com/launch?a=1&amp;b=2"`,
+		`name="oauth_signature" value="`,
+		`name="context_title" value="&#34;Design&#34; &lt;of&gt; &amp; Environments"`,
+		`document.forms[0].submit()`,
+	} {
+		if !strings.Contains(html, s) {
+			t.Errorf("Form should contain %s\n%s", s, html)
+		}
+	}
+}
+
+func TestHiddenInputs(t *testing.T) {
+	params := url.Values{
+		"b":       {"2", "3"},
+		"a":       {`x"><script>`},
+		`n"ame<>`: {"v"},
+	}
+	tpl := template.Must(template.New("t").Funcs(FuncMap).Parse(`{{ltiHiddenInputs .}}`))
+	var b strings.Builder
+	if err := tpl.Execute(&b, params); err != nil {
+		t.Fatalf("Error rendering inputs %s", err)
+	}
+	expected := `<input type="hidden" name="a" value="x&#34;&gt;&lt;script&gt;">
+<input type="hidden" name="b" value="2">
+<input type="hidden" name="b" value="3">
+<input type="hidden" name="n&#34;ame&lt;&gt;" value="v">
+`
+	if b.String() != expected {
+		t.Errorf("Unexpected inputs\n%s\nexpected\n%s", b.String(), expected)
+	}
+}
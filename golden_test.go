@@ -0,0 +1,99 @@
+package lti
+
+import (
+	"flag"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jordic/lti/oauth"
+)
+
+var update = flag.Bool("update", false, "update golden files in testdata/")
+
+// golden compares got with the contents of testdata/name.golden.
+// Run the tests with -update to rewrite the files after an
+// intended change of the wire output.
+func golden(t *testing.T, name string, got []byte) {
+	t.Helper()
+	path := filepath.Join("testdata", name+".golden")
+	if *update {
+		if err := ioutil.WriteFile(path, got, 0644); err != nil {
+			t.Fatalf("Error updating golden file %s", err)
+		}
+		return
+	}
+	want, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Error reading golden file %s", err)
+	}
+	if string(got) != string(want) {
+		t.Errorf("%s doesn't match golden file\ngot:  %s\nwant: %s", name, got, want)
+	}
+}
+
+const goldenURL = "http://www.imsglobal.org/developers/LTI/test/v1p1/tool.php"
+
+func TestGoldenLaunchForm(t *testing.T) {
+	p := NewProvider("secret", goldenURL, WithConsumerKey("12345"))
+	p.SetParams(GenerateForm())
+	p.Add("custom_note", `"quoted" <b>&amp;</b>`)
+	if _, err := p.Sign(); err != nil {
+		t.Fatalf("Error signing %s", err)
+	}
+	html, err := p.AutoSubmitHTML()
+	if err != nil {
+		t.Fatalf("Error rendering form %s", err)
+	}
+	golden(t, "launchform.html", []byte(html))
+}
+
+func TestGoldenAuthorizationHeader(t *testing.T) {
+	p := NewProvider("secret", "", WithConsumerKey("12345"),
+		WithSigner(oauth.GetHMAC256Signer("secret", "")),
+		WithNonceSource(fixedNonce("93ac608e18a7d41dec8f7219e1bf6a17")),
+		WithClock(fixedClock(time.Unix(1348093590, 0))))
+	r, _ := http.NewRequest("POST", "http://lms.example.com/outcomes?b64=MTIzNDU%3D",
+		strings.NewReader(`<?xml version="1.0" encoding="UTF-8"?><imsx_POXEnvelopeRequest/>`))
+	r.Header.Set("Content-Type", "application/xml")
+	if err := p.SignRequest(r); err != nil {
+		t.Fatalf("Error signing %s", err)
+	}
+	golden(t, "authorization", []byte(r.Header.Get("Authorization")))
+}
+
+// baseStringSigner records the base string signed by Signer.
+type baseStringSigner struct {
+	oauth.OauthSigner
+	base string
+}
+
+func (s *baseStringSigner) GetSignature(baseString string) (string, error) {
+	s.base = baseString
+	return s.OauthSigner.GetSignature(baseString)
+}
+
+func TestGoldenBaseString(t *testing.T) {
+	s := &baseStringSigner{OauthSigner: oauth.GetHMACSigner("secret", "")}
+	p := NewProvider("secret", "", WithConsumerKey("12345"), WithSigner(s),
+		WithNonceSource(fixedNonce("93ac608e18a7d41dec8f7219e1bf6a17")),
+		WithClock(fixedClock(time.Unix(1348093590, 0))))
+	form := GenerateForm()
+	for k := range form {
+		if strings.HasPrefix(k, "oauth_") {
+			form.Del(k)
+		}
+	}
+	// custom_id is sent in the query and the body, both are signed
+	form.Set("custom_id", "body")
+	r, _ := http.NewRequest("POST", goldenURL+"?custom_id=query&b64=MTIzNDU%3D",
+		strings.NewReader(form.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if err := p.SignRequest(r); err != nil {
+		t.Fatalf("Error signing %s", err)
+	}
+	golden(t, "basestring", []byte(s.base))
+}
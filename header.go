@@ -0,0 +1,36 @@
+package lti
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/jordic/lti/oauth"
+)
+
+// requestParams returns the params of r to be signed: the query,
+// the form body and the OAuth Authorization header ones. When
+// maxSize is set, bodies larger than it are rejected with
+// ErrFormTooLarge.
+func requestParams(r *http.Request, maxSize int64) (url.Values, error) {
+	if maxSize > 0 && r.Body != nil && r.Form == nil {
+		r.Body = http.MaxBytesReader(nil, r.Body, maxSize)
+	}
+	if err := r.ParseForm(); err != nil {
+		var mbe *http.MaxBytesError
+		if errors.As(err, &mbe) {
+			return nil, fmt.Errorf("%w, limit is %d bytes", ErrFormTooLarge, mbe.Limit)
+		}
+	}
+	h := r.Header.Get("Authorization")
+	if len(h) < 6 || !strings.EqualFold(h[:6], "oauth ") {
+		return r.Form, nil
+	}
+	kvs, err := oauth.CollectParameters(r)
+	if err != nil {
+		return nil, err
+	}
+	return oauth.ToValues(kvs), nil
+}
@@ -0,0 +1,137 @@
+package lti
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/jordic/lti/oauth"
+)
+
+func TestParseAuthHeader(t *testing.T) {
+	r, _ := http.NewRequest("POST", "http://urltest.com/?a=1", nil)
+	r.Header.Set("Authorization", `OAuth realm="Example", oauth_consumer_key="0685bd9184jfhq22",`+
+		`oauth_signature="wOJIO9A2W5mFwDgiDvZbTSMK%2FPY%3D",oauth_nonce="4572616e48616d6d65724c61686176"`)
+	v, err := requestParams(r, 0)
+	if err != nil {
+		t.Fatalf("Error parsing header %s", err)
+	}
+	if v.Get("realm") != "" {
+		t.Error("Realm should not be returned")
+	}
+	if v.Get("oauth_consumer_key") != "0685bd9184jfhq22" || v.Get("oauth_signature") != "wOJIO9A2W5mFwDgiDvZbTSMK/PY=" ||
+		v.Get("a") != "1" {
+		t.Errorf("Wrong params %v", v)
+	}
+
+	for _, h := range []string{`OAuth oauth_nonce=abc`, `OAuth oauth_nonce="abc`} {
+		r.Header.Set("Authorization", h)
+		if _, err := requestParams(r, 0); err == nil {
+			t.Errorf("Header %s should fail", h)
+		}
+	}
+}
+
+func TestIsValidAuthHeader(t *testing.T) {
+	c := NewProvider("asdf", "http://urltest.com/", WithConsumerKey("12345"))
+	c.Add("resource_link_id", "1")
+	c.Sign()
+
+	body := url.Values{}
+	var params []string
+	for k := range c.Params() {
+		if strings.HasPrefix(k, "oauth_") {
+			params = append(params, fmt.Sprintf(`%s="%s"`, k, url.QueryEscape(c.Get(k))))
+			continue
+		}
+		body.Set(k, c.Get(k))
+	}
+
+	r, _ := http.NewRequest("POST", "http://urltest.com/", strings.NewReader(body.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	r.Header.Set("Authorization", `OAuth realm="test", `+strings.Join(params, ", "))
+
+	p := NewProvider("asdf", "http://urltest.com/", WithConsumerKey("12345"))
+	if ok, err := p.IsValid(r); !ok {
+		t.Errorf("Request signed in the header should be valid, got %s", err)
+	}
+}
+
+func TestIsValidBodyHash(t *testing.T) {
+	body := `<?xml version="1.0" encoding="UTF-8"?><imsx_POXEnvelopeRequest/>`
+	sum := sha1.Sum([]byte(body))
+
+	c := NewProvider("asdf", "http://urltest.com/outcomes", WithConsumerKey("12345"))
+	c.Add("oauth_body_hash", base64.StdEncoding.EncodeToString(sum[:]))
+	c.Sign()
+
+	var params []string
+	for k := range c.Params() {
+		params = append(params, fmt.Sprintf(`%s="%s"`, k, url.QueryEscape(c.Get(k))))
+	}
+	header := "OAuth " + strings.Join(params, ", ")
+
+	p := NewProvider("asdf", "http://urltest.com/outcomes", WithConsumerKey("12345"))
+	for _, tt := range []struct {
+		body  string
+		valid bool
+	}{
+		{body, true},
+		{body + " ", false},
+	} {
+		r, _ := http.NewRequest("POST", "http://urltest.com/outcomes", strings.NewReader(tt.body))
+		r.Header.Set("Content-Type", "application/xml")
+		r.Header.Set("Authorization", header)
+		ok, err := p.IsValid(r)
+		if ok != tt.valid {
+			t.Errorf("Body %q: expected %v, got %v (%v)", tt.body, tt.valid, ok, err)
+		}
+		if !tt.valid && !errors.Is(err, ErrInvalidBodyHash) {
+			t.Errorf("Expected ErrInvalidBodyHash, got %v", err)
+		}
+		if !tt.valid && !strings.Contains(err.Error(), c.Get("oauth_body_hash")+", expected") {
+			t.Errorf("The hash claimed should be reported before the expected one, got %s", err)
+		}
+		if b, _ := ioutil.ReadAll(r.Body); string(b) != tt.body {
+			t.Error("Body should be readable after verification")
+		}
+	}
+
+	p.MaxFormSize = int64(len(body) - 1)
+	r, _ := http.NewRequest("POST", "http://urltest.com/outcomes", strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/xml")
+	r.Header.Set("Authorization", header)
+	// parsed by a middleware, the body is only limited by the hash
+	r.ParseForm()
+	if _, err := p.IsValid(r); !errors.Is(err, ErrFormTooLarge) {
+		t.Errorf("Body over MaxFormSize should be rejected, got %v", err)
+	}
+}
+
+func TestIsValidBodyHashSHA256(t *testing.T) {
+	body := []byte(`<?xml version="1.0" encoding="UTF-8"?><imsx_POXEnvelopeRequest/>`)
+	key, token := "12345", ""
+	o := &oauth.OAuthParameters{
+		Signer:      oauth.GetHMAC256Signer("asdf", ""),
+		ConsumerKey: &key,
+		Token:       &token,
+	}
+	r, err := o.NewRequest(context.Background(), "POST", "http://urltest.com/outcomes", nil,
+		"application/xml", body)
+	if err != nil {
+		t.Fatalf("Error signing %s", err)
+	}
+
+	p := NewProvider("asdf", "http://urltest.com/outcomes", WithConsumerKey("12345"),
+		WithSigner(oauth.GetHMAC256Signer("asdf", "")))
+	if ok, err := p.IsValid(r); !ok {
+		t.Errorf("HMAC-SHA256 request with a SHA-256 body hash should be valid, got %s", err)
+	}
+}
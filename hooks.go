@@ -0,0 +1,50 @@
+package lti
+
+// Hooks are called by a Provider on the outcome of its operations,
+// and by a VerifyPool with its stats, to feed metrics without
+// wrapping every call site:
+//
+//  p.Hooks = &lti.Hooks{
+//    OnValidationFailure: func(key string, err error) {
+//      failures.WithLabelValues(key, reason(err)).Inc()
+//    },
+//  }
+//
+// Any of them can be nil. They are called synchronously, so they
+// should be fast.
+type Hooks struct {
+	// OnValidationSuccess is called for every valid launch.
+	OnValidationSuccess func(consumerKey string)
+	// OnValidationFailure is called for every failed check of a
+	// launch, with its error, that wraps the Err* values of the
+	// package.
+	OnValidationFailure func(consumerKey string, reason error)
+	// OnSign is called after signing a message or request.
+	OnSign func(consumerKey string)
+	// OnPoolStats is called by a VerifyPool with its stats, every
+	// time a launch is rejected or given a worker.
+	OnPoolStats func(s PoolStats)
+}
+
+func (p *Provider) validationHooks(consumerKey string, errs []error) {
+	if p.Hooks == nil {
+		return
+	}
+	if len(errs) == 0 {
+		if p.Hooks.OnValidationSuccess != nil {
+			p.Hooks.OnValidationSuccess(consumerKey)
+		}
+		return
+	}
+	if p.Hooks.OnValidationFailure != nil {
+		for _, err := range errs {
+			p.Hooks.OnValidationFailure(consumerKey, err)
+		}
+	}
+}
+
+func (p *Provider) signHook(consumerKey string) {
+	if p.Hooks != nil && p.Hooks.OnSign != nil {
+		p.Hooks.OnSign(consumerKey)
+	}
+}
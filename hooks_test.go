@@ -0,0 +1,44 @@
+package lti
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestHooks(t *testing.T) {
+	var success, signed []string
+	var failures []error
+	hooks := &Hooks{
+		OnValidationSuccess: func(key string) { success = append(success, key) },
+		OnValidationFailure: func(key string, err error) { failures = append(failures, err) },
+		OnSign:              func(key string) { signed = append(signed, key) },
+	}
+
+	c := NewProvider("asdf", "http://urltest.com/", WithConsumerKey("12345"))
+	c.Hooks = hooks
+	c.Add("resource_link_id", "1")
+	c.Sign()
+	r, _ := http.NewRequest("POST", "http://urltest.com/service", nil)
+	c.SignRequest(r)
+	if len(signed) != 2 || signed[0] != "12345" {
+		t.Errorf("OnSign should be called twice, got %v", signed)
+	}
+
+	p := NewProvider("asdf", "http://urltest.com/", WithConsumerKey("12345"))
+	p.Hooks = hooks
+	p.ClockSkew = DefaultClockSkew
+	p.IsValid(&http.Request{Method: "POST", Form: c.Params()})
+	if len(success) != 1 || success[0] != "12345" {
+		t.Errorf("OnValidationSuccess should be called with the key, got %v", success)
+	}
+
+	form := c.Params()
+	form.Set("oauth_signature", "forged")
+	form.Set("oauth_timestamp", "1348093590")
+	p.Validate(&http.Request{Method: "POST", Form: form})
+	if len(failures) != 2 || !errors.Is(failures[0], ErrInvalidSignature) ||
+		!errors.Is(failures[1], ErrExpiredTimestamp) {
+		t.Errorf("OnValidationFailure should be called by failure, got %v", failures)
+	}
+}
@@ -0,0 +1,34 @@
+package lti
+
+import (
+	"net/http"
+	"net/url"
+)
+
+// LaunchVerifier verifies incoming launch requests, returning the
+// Provider holding the params of the launch, that can be nil when
+// the launch was not read. Provider and VerifyPool implement it,
+// applications can wrap them, to add caching or logging, or replace
+// them in tests. ToolSet, VerifyPool and DiagnosticHandler verify
+// their launches with one.
+type LaunchVerifier interface {
+	Verify(r *http.Request) (*Provider, error)
+}
+
+// RequestSigner signs outgoing requests, like Provider.SignRequest.
+type RequestSigner interface {
+	SignRequest(r *http.Request) error
+}
+
+// LaunchSource gives access to the params of a launch.
+type LaunchSource interface {
+	Get(k string) string
+	Params() url.Values
+}
+
+var (
+	_ LaunchVerifier = (*Provider)(nil)
+	_ LaunchVerifier = (*VerifyPool)(nil)
+	_ RequestSigner  = (*Provider)(nil)
+	_ LaunchSource   = (*Provider)(nil)
+)
@@ -0,0 +1,55 @@
+package lti
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+// launchValues is a LaunchSource not backed by a Provider.
+type launchValues url.Values
+
+func (l launchValues) Get(k string) string { return url.Values(l).Get(k) }
+func (l launchValues) Params() url.Values  { return url.Values(l) }
+
+func TestLaunchSource(t *testing.T) {
+	l := LinkFromLaunch(launchValues(GenerateForm()), "quiz-1")
+	if l.ResultSourcedID != "feb-123-456-2929::28883" || l.ConsumerKey != "12345" {
+		t.Errorf("Wrong link from launch source %#v", l)
+	}
+}
+
+// countingVerifier is a LaunchVerifier wrapping another one.
+type countingVerifier struct {
+	LaunchVerifier
+	n int
+}
+
+func (v *countingVerifier) Verify(r *http.Request) (*Provider, error) {
+	v.n++
+	return v.LaunchVerifier.Verify(r)
+}
+
+func TestLaunchVerifier(t *testing.T) {
+	v := &countingVerifier{LaunchVerifier: NewProvider("asdf", "http://urltest.com/quiz",
+		WithConsumerKey("12345"))}
+	ts := NewToolSet(v)
+	ts.Handle("/quiz", func(w http.ResponseWriter, r *http.Request, p *Provider) {
+		w.Write([]byte("quiz " + p.Get("resource_link_id")))
+	})
+	w := httptest.NewRecorder()
+	ts.ServeHTTP(w, launchRequest(t, "http://urltest.com/quiz", "Learner"))
+	if w.Code != http.StatusOK || w.Body.String() != "quiz 1" || v.n != 1 {
+		t.Errorf("Launch should be verified by the wrapper, got %d %s", w.Code, w.Body.String())
+	}
+
+	h := NewVerifyPool(v, 1, 0).Handler(func(w http.ResponseWriter, r *http.Request, p *Provider) {
+		w.Write([]byte("pool " + p.Get("resource_link_id")))
+	})
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, launchRequest(t, "http://urltest.com/quiz", "Learner"))
+	if w.Code != http.StatusOK || w.Body.String() != "pool 1" || v.n != 2 {
+		t.Errorf("Pool should verify with the wrapper, got %d %s", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,34 @@
+package lti
+
+import (
+	"encoding/json"
+	"net/url"
+)
+
+// MarshalJSON encodes the params of the provider as a JSON object of
+// arrays, keeping repeated params, so verified launches can be
+// stored or forwarded:
+//
+//  {"oauth_consumer_key":["12345"],"roles":["Instructor"]}
+//
+// The configuration of the provider, including its Secret, is not
+// encoded.
+func (p *Provider) MarshalJSON() ([]byte, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.values == nil {
+		return []byte("{}"), nil
+	}
+	return json.Marshal(p.values)
+}
+
+// UnmarshalJSON replaces the params of the provider with the ones
+// encoded by MarshalJSON, keeping its configuration.
+func (p *Provider) UnmarshalJSON(b []byte) error {
+	v := url.Values{}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	p.SetParams(v)
+	return nil
+}
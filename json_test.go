@@ -0,0 +1,42 @@
+package lti
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestProviderJSON(t *testing.T) {
+	p := NewProvider("asdf", "http://urltest.com/", WithConsumerKey("12345"))
+	p.SetParams(GenerateForm())
+	p.Params().Add("ext_multi", "a")
+	p.Params().Add("ext_multi", "b")
+	p.Sign()
+
+	b, err := json.Marshal(p)
+	if err != nil {
+		t.Fatalf("Error marshaling %s", err)
+	}
+	if strings.Contains(string(b), "asdf") {
+		t.Errorf("Secret should not be marshaled: %s", b)
+	}
+
+	c := NewProvider("asdf", "http://urltest.com/", WithConsumerKey("12345"))
+	if err := json.Unmarshal(b, c); err != nil {
+		t.Fatalf("Error unmarshaling %s", err)
+	}
+	if !reflect.DeepEqual(c.Params(), p.Params()) {
+		t.Errorf("Params don't match\n%v\n%v", c.Params(), p.Params())
+	}
+	if c.Secret != "asdf" || c.ConsumerKey != "12345" {
+		t.Error("Configuration should be kept")
+	}
+
+	if b, _ := json.Marshal(&Provider{}); string(b) != "{}" {
+		t.Errorf("Empty provider should marshal to {}, got %s", b)
+	}
+	if err := json.Unmarshal([]byte(`{"a":"b"}`), c); err == nil {
+		t.Error("Invalid params should fail")
+	}
+}
@@ -0,0 +1,124 @@
+package lti
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// ErrLaunchNotFound is returned by LaunchStore.LoadLaunch when there
+// is no launch with the id given.
+var ErrLaunchNotFound = errors.New("launch not found")
+
+// Launch is the context of a verified launch, kept along the
+// session of the user, so results can be sent long after the
+// launch request.
+type Launch struct {
+	ID                string     `json:"id"`
+	ConsumerKey       string     `json:"consumer_key"`
+	UserID            string     `json:"user_id"`
+	Roles             string     `json:"roles,omitempty"`
+	ContextID         string     `json:"context_id,omitempty"`
+	ResourceLinkID    string     `json:"resource_link_id"`
+	OutcomeServiceURL string     `json:"outcome_service_url,omitempty"`
+	ResultSourcedID   string     `json:"result_sourcedid,omitempty"`
+	Params            url.Values `json:"params"`
+	Created           time.Time  `json:"created"`
+}
+
+// NewLaunch returns the Launch held by p, usually a verified
+// Provider, with a new random ID. Params is a copy of the params
+// of p.
+func NewLaunch(p LaunchSource) *Launch {
+	return &Launch{
+		ID:                launchID(),
+		ConsumerKey:       p.Get("oauth_consumer_key"),
+		UserID:            p.Get("user_id"),
+		Roles:             p.Get("roles"),
+		ContextID:         p.Get("context_id"),
+		ResourceLinkID:    p.Get("resource_link_id"),
+		OutcomeServiceURL: p.Get("lis_outcome_service_url"),
+		ResultSourcedID:   p.Get("lis_result_sourcedid"),
+		Params:            copyValues(p.Params()),
+		Created:           time.Now(),
+	}
+}
+
+// launchID returns a random launch id.
+func launchID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// LaunchStore persists verified launches by their ID, usually kept
+// in the session of the user.
+type LaunchStore interface {
+	// SaveLaunch creates or replaces a launch.
+	SaveLaunch(ctx context.Context, l *Launch) error
+	// LoadLaunch returns the launch with the id given, or
+	// ErrLaunchNotFound.
+	LoadLaunch(ctx context.Context, id string) (*Launch, error)
+}
+
+// MemoryLaunchStore is a LaunchStore kept in memory. It's a Purger,
+// so its launches can be expired by a Sweeper.
+type MemoryLaunchStore struct {
+	mu       sync.RWMutex
+	launches map[string]Launch
+}
+
+// NewMemoryLaunchStore returns an empty MemoryLaunchStore.
+func NewMemoryLaunchStore() *MemoryLaunchStore {
+	return &MemoryLaunchStore{launches: map[string]Launch{}}
+}
+
+// SaveLaunch creates or replaces a launch.
+func (s *MemoryLaunchStore) SaveLaunch(ctx context.Context, l *Launch) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c := *l
+	c.Params = copyValues(l.Params)
+	s.launches[l.ID] = c
+	return nil
+}
+
+// LoadLaunch returns the launch with the id given.
+func (s *MemoryLaunchStore) LoadLaunch(ctx context.Context, id string) (*Launch, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	l, ok := s.launches[id]
+	if !ok {
+		return nil, ErrLaunchNotFound
+	}
+	l.Params = copyValues(l.Params)
+	return &l, nil
+}
+
+// Purge removes the launches created before the time given, so a
+// Sweeper can expire them.
+func (s *MemoryLaunchStore) Purge(ctx context.Context, before time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := 0
+	for id, l := range s.launches {
+		if l.Created.Before(before) {
+			delete(s.launches, id)
+			n++
+		}
+	}
+	return n, nil
+}
+
+// copyValues returns a deep copy of v.
+func copyValues(v url.Values) url.Values {
+	c := make(url.Values, len(v))
+	for k, vs := range v {
+		c[k] = append([]string(nil), vs...)
+	}
+	return c
+}
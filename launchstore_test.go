@@ -0,0 +1,57 @@
+package lti
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestMemoryLaunchStore(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryLaunchStore()
+
+	p := NewProvider("secret", "http://localhost")
+	p.SetParams(GenerateForm())
+	l := NewLaunch(p)
+	if l.ID == "" || l.ID == NewLaunch(p).ID {
+		t.Errorf("Launches should get unique ids, got %s", l.ID)
+	}
+	if l.UserID != "292832126" || l.ResultSourcedID != "feb-123-456-2929::28883" ||
+		l.ContextID != "456434513" || l.Roles != "Instructor" {
+		t.Errorf("Wrong launch %#v", l)
+	}
+
+	if err := s.SaveLaunch(ctx, l); err != nil {
+		t.Fatalf("Error saving launch %s", err)
+	}
+	p.Add("user_id", "other")
+	got, err := s.LoadLaunch(ctx, l.ID)
+	if err != nil {
+		t.Fatalf("Error loading launch %s", err)
+	}
+	if got.Params.Get("user_id") != "292832126" {
+		t.Error("Launch params should not be shared with the provider")
+	}
+
+	b, err := json.Marshal(got)
+	if err != nil {
+		t.Fatalf("Error marshaling launch %s", err)
+	}
+	var decoded Launch
+	if err := json.Unmarshal(b, &decoded); err != nil {
+		t.Fatalf("Error unmarshaling launch %s", err)
+	}
+	if !decoded.Created.Equal(got.Created) {
+		t.Error("Created should survive JSON")
+	}
+	decoded.Created = got.Created
+	if !reflect.DeepEqual(&decoded, got) {
+		t.Errorf("Launch should survive JSON\n%#v\n%#v", decoded, got)
+	}
+
+	if _, err := s.LoadLaunch(ctx, "missing"); !errors.Is(err, ErrLaunchNotFound) {
+		t.Errorf("Expected ErrLaunchNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,176 @@
+package lti
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Link ties an activity of the tool to the gradebook of a consumer:
+// a resource link, and the user the result belongs to.
+type Link struct {
+	ActivityID        string
+	ConsumerKey       string
+	ResourceLinkID    string
+	UserID            string
+	ResultSourcedID   string
+	OutcomeServiceURL string
+	// LineItemURL is the AGS line item, for LTI 1.3 consumers.
+	LineItemURL string
+}
+
+// LinkFromLaunch returns the Link of the launch held by p, usually
+// a Provider, to the activity given.
+func LinkFromLaunch(p LaunchSource, activityID string) Link {
+	return Link{
+		ActivityID:        activityID,
+		ConsumerKey:       p.Get("oauth_consumer_key"),
+		ResourceLinkID:    p.Get("resource_link_id"),
+		UserID:            p.Get("user_id"),
+		ResultSourcedID:   p.Get("lis_result_sourcedid"),
+		OutcomeServiceURL: p.Get("lis_outcome_service_url"),
+	}
+}
+
+// LinkStore persists the links between the tool activities and the
+// consumer gradebooks, needed to send results long after a launch.
+// A link is identified by its consumer key, resource link id and
+// user id, saving it again replaces it.
+type LinkStore interface {
+	// SaveLink creates or replaces a link.
+	SaveLink(ctx context.Context, l Link) error
+	// Links returns every link of an activity.
+	Links(ctx context.Context, activityID string) ([]Link, error)
+}
+
+// MemoryLinkStore is a LinkStore kept in memory.
+type MemoryLinkStore struct {
+	mu    sync.RWMutex
+	links map[[3]string]Link
+}
+
+// NewMemoryLinkStore returns an empty MemoryLinkStore.
+func NewMemoryLinkStore() *MemoryLinkStore {
+	return &MemoryLinkStore{links: map[[3]string]Link{}}
+}
+
+// SaveLink creates or replaces a link.
+func (s *MemoryLinkStore) SaveLink(ctx context.Context, l Link) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.links[[3]string{l.ConsumerKey, l.ResourceLinkID, l.UserID}] = l
+	return nil
+}
+
+// Links returns every link of an activity.
+func (s *MemoryLinkStore) Links(ctx context.Context, activityID string) ([]Link, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var res []Link
+	for _, l := range s.links {
+		if l.ActivityID == activityID {
+			res = append(res, l)
+		}
+	}
+	return res, nil
+}
+
+// SQLLinkStore is a LinkStore backed by a sql database, storing
+// the links in Table, that should be created as:
+//
+//  CREATE TABLE lti_links (
+//    activity_id VARCHAR(255) NOT NULL,
+//    consumer_key VARCHAR(255) NOT NULL,
+//    resource_link_id VARCHAR(255) NOT NULL,
+//    user_id VARCHAR(255) NOT NULL,
+//    result_sourcedid TEXT,
+//    outcome_service_url TEXT,
+//    line_item_url TEXT,
+//    PRIMARY KEY (consumer_key, resource_link_id, user_id)
+//  );
+type SQLLinkStore struct {
+	DB *sql.DB
+	// Table defaults to lti_links.
+	Table string
+	// Dollar makes queries use $1 placeholders (postgres)
+	// instead of ?.
+	Dollar bool
+}
+
+const linkColumns = "activity_id, consumer_key, resource_link_id, user_id, " +
+	"result_sourcedid, outcome_service_url, line_item_url"
+
+// query replaces the table name and placeholders of q.
+func (s *SQLLinkStore) query(q string) string {
+	table := s.Table
+	if table == "" {
+		table = "lti_links"
+	}
+	q = strings.Replace(q, "{table}", table, -1)
+	if !s.Dollar {
+		return q
+	}
+	var b strings.Builder
+	n := 0
+	for _, c := range q {
+		if c == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
+
+// SaveLink creates or replaces a link.
+func (s *SQLLinkStore) SaveLink(ctx context.Context, l Link) error {
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, s.query("DELETE FROM {table} "+
+		"WHERE consumer_key = ? AND resource_link_id = ? AND user_id = ?"),
+		l.ConsumerKey, l.ResourceLinkID, l.UserID)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, s.query("INSERT INTO {table} ("+linkColumns+") "+
+		"VALUES (?, ?, ?, ?, ?, ?, ?)"),
+		l.ActivityID, l.ConsumerKey, l.ResourceLinkID, l.UserID,
+		l.ResultSourcedID, l.OutcomeServiceURL, l.LineItemURL)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Links returns every link of an activity.
+func (s *SQLLinkStore) Links(ctx context.Context, activityID string) ([]Link, error) {
+	rows, err := s.DB.QueryContext(ctx, s.query("SELECT "+linkColumns+
+		" FROM {table} WHERE activity_id = ?"), activityID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var res []Link
+	for rows.Next() {
+		var l Link
+		var sourcedID, serviceURL, lineItem sql.NullString
+		err := rows.Scan(&l.ActivityID, &l.ConsumerKey, &l.ResourceLinkID,
+			&l.UserID, &sourcedID, &serviceURL, &lineItem)
+		if err != nil {
+			return nil, err
+		}
+		l.ResultSourcedID = sourcedID.String
+		l.OutcomeServiceURL = serviceURL.String
+		l.LineItemURL = lineItem.String
+		res = append(res, l)
+	}
+	return res, rows.Err()
+}
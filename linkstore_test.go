@@ -0,0 +1,239 @@
+package lti
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestMemoryLinkStore(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryLinkStore()
+
+	p := NewProvider("secret", "http://localhost")
+	p.SetParams(GenerateForm())
+	l := LinkFromLaunch(p, "quiz-1")
+	if l.ResultSourcedID != "feb-123-456-2929::28883" || l.UserID != "292832126" {
+		t.Errorf("Wrong link from launch %#v", l)
+	}
+
+	s.SaveLink(ctx, l)
+	l.ResultSourcedID = "updated"
+	s.SaveLink(ctx, l)
+	s.SaveLink(ctx, Link{ActivityID: "quiz-1", ConsumerKey: "12345", ResourceLinkID: "2", UserID: "1"})
+	s.SaveLink(ctx, Link{ActivityID: "quiz-2", ConsumerKey: "12345", ResourceLinkID: "3", UserID: "1"})
+
+	links, err := s.Links(ctx, "quiz-1")
+	if err != nil {
+		t.Fatalf("Error loading links %s", err)
+	}
+	if len(links) != 2 {
+		t.Fatalf("Expected 2 links, got %v", links)
+	}
+	for _, l := range links {
+		if l.ResourceLinkID == "120988f929-274612" && l.ResultSourcedID != "updated" {
+			t.Errorf("Link should be replaced, got %#v", l)
+		}
+	}
+}
+
+func TestSQLLinkStoreQuery(t *testing.T) {
+	s := &SQLLinkStore{Table: "links", Dollar: true}
+	q := s.query("SELECT 1 FROM {table} WHERE a = ? AND b = ?")
+	if q != "SELECT 1 FROM links WHERE a = $1 AND b = $2" {
+		t.Errorf("Wrong query %s", q)
+	}
+	s = &SQLLinkStore{}
+	q = s.query("SELECT 1 FROM {table} WHERE a = ?")
+	if q != "SELECT 1 FROM lti_links WHERE a = ?" {
+		t.Errorf("Wrong query %s", q)
+	}
+}
+
+// linkDB is a database/sql driver keeping the lti_links table in
+// memory, understanding only the queries of SQLLinkStore.
+type linkDB struct {
+	mu      sync.Mutex
+	rows    [][]driver.Value
+	queries []string
+	// fail makes the queries starting with it fail.
+	fail       string
+	rolledBack bool
+}
+
+var errLinkDB = errors.New("linkdb: query failed")
+
+func (db *linkDB) Connect(ctx context.Context) (driver.Conn, error) { return linkConn{db}, nil }
+func (db *linkDB) Driver() driver.Driver                            { return nil }
+
+type linkConn struct{ db *linkDB }
+
+func (c linkConn) Prepare(q string) (driver.Stmt, error) { return linkStmt{c.db, q}, nil }
+func (c linkConn) Close() error                          { return nil }
+func (c linkConn) Begin() (driver.Tx, error)             { return linkTx{c.db}, nil }
+
+type linkTx struct{ db *linkDB }
+
+func (tx linkTx) Commit() error { return nil }
+func (tx linkTx) Rollback() error {
+	tx.db.mu.Lock()
+	defer tx.db.mu.Unlock()
+	tx.db.rolledBack = true
+	return nil
+}
+
+type linkStmt struct {
+	db *linkDB
+	q  string
+}
+
+func (s linkStmt) Close() error  { return nil }
+func (s linkStmt) NumInput() int { return -1 }
+
+// run records the query, failing it when asked to.
+func (s linkStmt) run() error {
+	s.db.queries = append(s.db.queries, s.q)
+	if s.db.fail != "" && strings.HasPrefix(s.q, s.db.fail) {
+		return errLinkDB
+	}
+	return nil
+}
+
+func (s linkStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+	if err := s.run(); err != nil {
+		return nil, err
+	}
+	switch {
+	case strings.HasPrefix(s.q, "DELETE"):
+		kept := s.db.rows[:0]
+		for _, r := range s.db.rows {
+			if r[1] != args[0] || r[2] != args[1] || r[3] != args[2] {
+				kept = append(kept, r)
+			}
+		}
+		n := len(s.db.rows) - len(kept)
+		s.db.rows = kept
+		return driver.RowsAffected(n), nil
+	case strings.HasPrefix(s.q, "INSERT"):
+		s.db.rows = append(s.db.rows, append([]driver.Value(nil), args...))
+		return driver.RowsAffected(1), nil
+	}
+	return nil, fmt.Errorf("linkdb: unexpected exec %s", s.q)
+}
+
+func (s linkStmt) Query(args []driver.Value) (driver.Rows, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+	if err := s.run(); err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(s.q, "SELECT") {
+		return nil, fmt.Errorf("linkdb: unexpected query %s", s.q)
+	}
+	rows := &linkRows{}
+	for _, r := range s.db.rows {
+		if r[0] == args[0] {
+			rows.rows = append(rows.rows, r)
+		}
+	}
+	return rows, nil
+}
+
+type linkRows struct {
+	rows [][]driver.Value
+}
+
+func (r *linkRows) Columns() []string { return strings.Split(linkColumns, ", ") }
+func (r *linkRows) Close() error      { return nil }
+func (r *linkRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
+
+func TestSQLLinkStore(t *testing.T) {
+	ctx := context.Background()
+	db := &linkDB{}
+	s := &SQLLinkStore{DB: sql.OpenDB(db)}
+	defer s.DB.Close()
+
+	l := Link{ActivityID: "quiz-1", ConsumerKey: "12345", ResourceLinkID: "1", UserID: "u1",
+		ResultSourcedID: "sid", OutcomeServiceURL: "http://lms/outcomes"}
+	if err := s.SaveLink(ctx, l); err != nil {
+		t.Fatalf("Error saving link %s", err)
+	}
+	l.ResultSourcedID = "updated"
+	if err := s.SaveLink(ctx, l); err != nil {
+		t.Fatalf("Error saving link %s", err)
+	}
+	s.SaveLink(ctx, Link{ActivityID: "quiz-2", ConsumerKey: "12345", ResourceLinkID: "2", UserID: "u1"})
+	// a row written by another application, with NULL columns
+	db.rows = append(db.rows, []driver.Value{"quiz-1", "12345", "3", "u2", nil, nil, nil})
+
+	links, err := s.Links(ctx, "quiz-1")
+	if err != nil {
+		t.Fatalf("Error loading links %s", err)
+	}
+	expected := []Link{
+		{ActivityID: "quiz-1", ConsumerKey: "12345", ResourceLinkID: "1", UserID: "u1",
+			ResultSourcedID: "updated", OutcomeServiceURL: "http://lms/outcomes"},
+		{ActivityID: "quiz-1", ConsumerKey: "12345", ResourceLinkID: "3", UserID: "u2"},
+	}
+	if !reflect.DeepEqual(links, expected) {
+		t.Errorf("Expected %#v, got %#v", expected, links)
+	}
+	if links, _ := s.Links(ctx, "other"); len(links) != 0 {
+		t.Errorf("Expected no links, got %v", links)
+	}
+}
+
+func TestSQLLinkStoreErrors(t *testing.T) {
+	ctx := context.Background()
+	l := Link{ActivityID: "quiz-1", ConsumerKey: "12345", ResourceLinkID: "1", UserID: "u1"}
+
+	for _, fail := range []string{"DELETE", "INSERT"} {
+		db := &linkDB{fail: fail}
+		s := &SQLLinkStore{DB: sql.OpenDB(db)}
+		if err := s.SaveLink(ctx, l); !errors.Is(err, errLinkDB) {
+			t.Errorf("Failed %s should be returned, got %v", fail, err)
+		}
+		if !db.rolledBack {
+			t.Errorf("Failed %s should roll back", fail)
+		}
+		s.DB.Close()
+	}
+
+	db := &linkDB{fail: "SELECT"}
+	s := &SQLLinkStore{DB: sql.OpenDB(db)}
+	defer s.DB.Close()
+	if _, err := s.Links(ctx, "quiz-1"); !errors.Is(err, errLinkDB) {
+		t.Errorf("Failed SELECT should be returned, got %v", err)
+	}
+}
+
+func TestSQLLinkStoreDollar(t *testing.T) {
+	ctx := context.Background()
+	db := &linkDB{}
+	s := &SQLLinkStore{DB: sql.OpenDB(db), Table: "links", Dollar: true}
+	defer s.DB.Close()
+
+	s.SaveLink(ctx, Link{ActivityID: "quiz-1", ConsumerKey: "12345", ResourceLinkID: "1", UserID: "u1"})
+	s.Links(ctx, "quiz-1")
+	for _, q := range db.queries {
+		if strings.Contains(q, "?") || !strings.Contains(q, " links ") || !strings.Contains(q, "$1") {
+			t.Errorf("Query should use the table and $n placeholders, got %s", q)
+		}
+	}
+}
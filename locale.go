@@ -0,0 +1,35 @@
+package lti
+
+import (
+	"strings"
+
+	"golang.org/x/text/language"
+)
+
+// ParseLocale parses an LTI locale into a language tag. Besides
+// BCP 47 tags (en-US), consumers send the underscore form (en_US),
+// and Moodle its language packs, that can add variants unknown to
+// BCP 47, like en_kids. Unknown trailing subtags are dropped,
+// keeping the language and region when possible. It returns
+// language.Und when no language can be parsed.
+func ParseLocale(locale string) language.Tag {
+	if t, err := language.Parse(locale); err == nil {
+		return t
+	}
+	parts := strings.FieldsFunc(locale, func(r rune) bool { return r == '-' || r == '_' })
+	for n := len(parts) - 1; n > 0; n-- {
+		if t, err := language.Parse(strings.Join(parts[:n], "-")); err == nil {
+			return t
+		}
+	}
+	return language.Und
+}
+
+// LocaleTag returns the launch_presentation_locale of the launch as
+// a language tag, as ParseLocale does, to localize the tool:
+//
+//  matcher := language.NewMatcher([]language.Tag{language.English, language.Spanish})
+//  tag, _, _ := matcher.Match(p.LocaleTag())
+func (p *Provider) LocaleTag() language.Tag {
+	return ParseLocale(p.Get("launch_presentation_locale"))
+}
@@ -0,0 +1,32 @@
+package lti
+
+import (
+	"testing"
+
+	"golang.org/x/text/language"
+)
+
+func TestParseLocale(t *testing.T) {
+	for locale, tag := range map[string]string{
+		"en-US":      "en-US",
+		"es_mx":      "es-MX",
+		"pt-br":      "pt-BR",
+		"en_kids":    "en",
+		"en_us_kids": "en-US",
+		"":           "und",
+		"??":         "und",
+	} {
+		if got := ParseLocale(locale).String(); got != tag {
+			t.Errorf("ParseLocale(%q) = %s, expected %s", locale, got, tag)
+		}
+	}
+
+	p := NewProvider("secret", "http://localhost")
+	p.SetParams(GenerateForm())
+	if tag := p.LocaleTag(); tag.String() != "en-US" {
+		t.Errorf("Expected en-US, got %s", tag)
+	}
+	if tag := NewProvider("secret", "http://localhost").LocaleTag(); tag != language.Und {
+		t.Errorf("Launch without locale should get und, got %s", tag)
+	}
+}
@@ -12,11 +12,14 @@ package lti
 import (
 	"crypto/rand"
 	"encoding/binary"
+	"errors"
 	"fmt"
+	"log"
 	"net/http"
 	"net/url"
 	"strconv"
 	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -46,57 +49,230 @@ const (
 //
 // A Provider also holds a internal params url.Values, that can
 // be accessed via Get, or Add.
+//
+// The params of a Provider are guarded, so its methods don't race,
+// but they are those of the last request verified or signed. A
+// single configured instance serving concurrent launches should
+// verify them with Verify, that keeps the params of each launch in
+// its own Provider.
 type Provider struct {
 	Secret      string
 	URL         string
 	ConsumerKey string
 	Method      string
+	mu          sync.RWMutex
 	values      url.Values
 	r           *http.Request
 	Signer      oauth.OauthSigner
+	// Verifier, when set, verifies the signatures of the launches
+	// instead of Signer, like the oauth.RSAVerifier of a consumer
+	// signing with RSA.
+	Verifier oauth.OauthVerifier
+	// URLPolicy tells how the launch URL can differ from URL
+	// and still be accepted by IsValid.
+	URLPolicy URLPolicy
+	// DetectURL makes IsValid accept launches signed with the URL
+	// the request was sent to, derived from the Host, or from the
+	// X-Forwarded-Proto and X-Forwarded-Host headers with
+	// TrustProxy.
+	DetectURL bool
+	// TrustProxy makes IsValid take the scheme and host of the
+	// requests from the X-Forwarded-Proto and X-Forwarded-Host
+	// headers. They are set by the client, so it must only be
+	// enabled behind a proxy that overwrites them on every request.
+	TrustProxy bool
+	// AllowGET makes IsValid accept signed launches sent as GET,
+	// with their params in the query string.
+	AllowGET bool
+	// AllowOAuth10a makes IsValid accept requests with
+	// oauth_version 1.0a, sent by some consumers.
+	AllowOAuth10a bool
+	// Nonces, when set, keeps the nonces seen by IsValid to
+	// reject replayed requests.
+	Nonces NonceStore
+	// Redaction sets the params masked by Redacted, instead of
+	// DefaultRedactionPolicy.
+	Redaction *RedactionPolicy
+	// Failures, when set, records the verification failures
+	// of IsValid by consumer key.
+	Failures FailureStore
+	// MaxFormSize, when set, limits the size in bytes of the
+	// request bodies read by IsValid, the forms and the bodies
+	// hashed. Otherwise the limit of http.Request.ParseForm, 10MB,
+	// applies.
+	MaxFormSize int64
+	// Debug logs the base strings, the signatures and the failed
+	// checks of IsValid and Sign to Logger, or to the standard
+	// logger when it is not set.
+	Debug  bool
+	Logger Logger
+	// Hooks, when set, are called on the outcome of IsValid,
+	// Validate and the signing methods.
+	Hooks *Hooks
+	// Privacy tells if IsValid strips or hashes the personal data
+	// of the user from the params, once verified.
+	Privacy PrivacyMode
+	// PrivacyKey is the key of the HMAC of PrivacyHash. It should
+	// be a random secret of its own, without it the personal data
+	// is stripped instead.
+	PrivacyKey []byte
+	// TokenKey is the key of the HMAC of the launch tokens. It
+	// should be a random secret of its own, without it no launch
+	// tokens are issued or verified.
+	TokenKey []byte
+	// ExtraOAuth tells what IsValid does with unexpected oauth_
+	// params, ExtraOAuthByConsumer overrides it by consumer key.
+	ExtraOAuth           ExtraOAuthPolicy
+	ExtraOAuthByConsumer map[string]ExtraOAuthPolicy
+	// LenientVersion makes ValidateLaunch accept any lti_version,
+	// for consumers sending non conformant ones.
+	LenientVersion bool
+	// ClockSkew, when set, is how far the oauth_timestamp of a
+	// request can be from the current time. When not set, the
+	// timestamp is only checked if Nonces is set, within
+	// DefaultClockSkew. Nonce stores must keep the nonces at least
+	// this long.
+	ClockSkew time.Duration
+	// AltURLs are launch URLs accepted by IsValid besides URL, for
+	// tools reachable at several hosts.
+	AltURLs []string
+	// LaunchURLs, when set, returns more launch URLs accepted for
+	// the request r, like URL rewritten for the host of r.
+	LaunchURLs func(r *http.Request) []string
+	// StrictMode makes ValidateLaunch and Validate run every LTI
+	// 1.1 conformance check, to self certify a consumer.
+	StrictMode bool
+	// Secrets, when set, is used by IsValid instead of ConsumerKey
+	// and Secret, verifying the launches of any of its consumers
+	// with each of their secrets, and the signature method of
+	// Signer, or HMAC-SHA1 if it doesn't sign with secrets.
+	Secrets SecretStore
+	// NormalizeUnicode makes Sign, SignRequest and IsValid sign
+	// the params normalized to NFC, for consumers signing NFC
+	// values delivered as NFD by proxies.
+	NormalizeUnicode bool
+	// AllowPlaintext makes IsValid accept launches signed with the
+	// PLAINTEXT method, that sends the secret as the signature,
+	// when received over https. Behind a proxy, X-Forwarded-Proto
+	// tells if it was, only with TrustProxy.
+	AllowPlaintext bool
+	// NonceSource and Clock, when set, give the nonces and the
+	// current time used signing and checking timestamps, for
+	// deterministic signatures in tests.
+	NonceSource oauth.NonceSource
+	Clock       oauth.Clock
+}
+
+// Logger receives the debug output of a Provider. *log.Logger
+// implements it.
+type Logger interface {
+	Printf(format string, v ...interface{})
 }
 
 // NewProvider is a provider configured with sensible defaults
 // as a signer the HMACSigner is used... (seems that is the most used)
-func NewProvider(secret, urlSrv string) *Provider {
+// The defaults can be changed passing options.
+func NewProvider(secret, urlSrv string, opts ...Option) *Provider {
 	sig := oauth.GetHMACSigner(secret, "")
-	return &Provider{
+	p := &Provider{
 		Secret: secret,
 		Method: "POST",
 		values: url.Values{},
 		Signer: sig,
 		URL:    urlSrv,
 	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// Clone returns a copy of p, with its own copy of the params, so a
+// provider configured once can be cloned for every request without
+// sharing them. The Signer and the stores are shared.
+func (p *Provider) Clone() *Provider {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	values := make(url.Values, len(p.values))
+	for k, vs := range p.values {
+		values[k] = append([]string(nil), vs...)
+	}
+	return &Provider{
+		Secret:               p.Secret,
+		URL:                  p.URL,
+		ConsumerKey:          p.ConsumerKey,
+		Method:               p.Method,
+		values:               values,
+		Signer:               p.Signer,
+		Verifier:             p.Verifier,
+		URLPolicy:            p.URLPolicy,
+		DetectURL:            p.DetectURL,
+		TrustProxy:           p.TrustProxy,
+		AllowGET:             p.AllowGET,
+		AllowOAuth10a:        p.AllowOAuth10a,
+		Nonces:               p.Nonces,
+		Redaction:            p.Redaction,
+		Failures:             p.Failures,
+		MaxFormSize:          p.MaxFormSize,
+		Debug:                p.Debug,
+		Logger:               p.Logger,
+		Hooks:                p.Hooks,
+		Privacy:              p.Privacy,
+		PrivacyKey:           p.PrivacyKey,
+		TokenKey:             p.TokenKey,
+		ExtraOAuth:           p.ExtraOAuth,
+		ExtraOAuthByConsumer: p.ExtraOAuthByConsumer,
+		LenientVersion:       p.LenientVersion,
+		ClockSkew:            p.ClockSkew,
+		AltURLs:              p.AltURLs,
+		LaunchURLs:           p.LaunchURLs,
+		StrictMode:           p.StrictMode,
+		Secrets:              p.Secrets,
+		NormalizeUnicode:     p.NormalizeUnicode,
+		AllowPlaintext:       p.AllowPlaintext,
+		NonceSource:          p.NonceSource,
+		Clock:                p.Clock,
+	}
 }
 
-// HasRole checks if a LTI request, has a provided role
+// HasRole checks if a LTI request, has a provided role.
+// The role can be given in its short form (Instructor), matching
+// it in any scope, or as a full URN (urn:lti:instrole:ims/lis/Administrator).
 func (p *Provider) HasRole(role string) bool {
-	ro := strings.Split(p.Get("roles"), ",")
-	roles := strings.Join(ro, " ") + " "
-	if strings.Contains(roles, role+" ") {
-		return true
+	for _, r := range ParseRoles(p.Get("roles")) {
+		if r.matches(role) {
+			return true
+		}
 	}
 	return false
 }
 
 // Get a value from the Params map in provider
 func (p *Provider) Get(k string) string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
 	return p.values.Get(k)
 }
 
 // Params returns the map of values stored on the LTI request
 func (p *Provider) Params() url.Values {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
 	return p.values
 }
 
 // SetParams for a provider
 func (p *Provider) SetParams(v url.Values) *Provider {
+	p.mu.Lock()
+	defer p.mu.Unlock()
 	p.values = v
 	return p
 }
 
 // Add a new param to a LTI request
 func (p *Provider) Add(k, v string) *Provider {
+	p.mu.Lock()
+	defer p.mu.Unlock()
 	if p.values == nil {
 		p.values = url.Values{}
 	}
@@ -104,8 +280,56 @@ func (p *Provider) Add(k, v string) *Provider {
 	return p
 }
 
+// Del removes a param of a LTI request
+func (p *Provider) Del(k string) *Provider {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.values != nil {
+		p.values.Del(k)
+	}
+	return p
+}
+
+// AddAll adds every param of v, replacing the values of the params
+// already defined, as Add does.
+func (p *Provider) AddAll(v url.Values) *Provider {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.values == nil {
+		p.values = url.Values{}
+	}
+	for k, vs := range v {
+		p.values[k] = append([]string(nil), vs...)
+	}
+	return p
+}
+
+// Merge adds every param of m, replacing the values of the params
+// already defined, as Add does.
+func (p *Provider) Merge(m map[string]string) *Provider {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.values == nil {
+		p.values = url.Values{}
+	}
+	for k, v := range m {
+		p.values.Set(k, v)
+	}
+	return p
+}
+
+// Has checks if a key is defined, even if empty
+func (p *Provider) Has(k string) bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	_, ok := p.values[k]
+	return ok
+}
+
 // Empty checks if a key is defined (or has something)
 func (p *Provider) Empty(key string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
 	if p.values == nil {
 		p.values = url.Values{}
 	}
@@ -115,52 +339,202 @@ func (p *Provider) Empty(key string) bool {
 // Sign a request, adding, required fields,
 // A request, can be drilled on a template, iterating, over p.Prams()
 func (p *Provider) Sign() (string, error) {
-	if p.Empty("oauth_version") {
-		p.Add("oauth_version", oAuthVersion)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.values == nil {
+		p.values = url.Values{}
 	}
-	if p.Empty("oauth_timestamp") {
-		p.Add("oauth_timestamp", strconv.FormatInt(time.Now().Unix(), 10))
+	if p.values.Get("oauth_version") == "" {
+		p.values.Set("oauth_version", oAuthVersion)
 	}
-	if p.Empty("oauth_nonce") {
-		p.Add("oauth_nonce", nonce())
+	if p.values.Get("oauth_timestamp") == "" {
+		p.values.Set("oauth_timestamp", strconv.FormatInt(p.now().Unix(), 10))
 	}
-	if p.Empty("oauth_signature_method") {
-		p.Add("oauth_signature_method", p.Signer.GetMethod())
+	if p.values.Get("oauth_nonce") == "" {
+		n, err := p.nonce()
+		if err != nil {
+			return "", err
+		}
+		p.values.Set("oauth_nonce", n)
+	}
+	if p.values.Get("oauth_signature_method") == "" {
+		p.values.Set("oauth_signature_method", p.Signer.GetMethod())
 	}
-	p.Add("oauth_consumer_key", p.ConsumerKey)
+	p.values.Set("oauth_consumer_key", p.ConsumerKey)
 
-	signature, err := Sign(p.values, p.URL, p.Method, p.Signer)
+	signature, err := p.sign(p.Signer, p.values, p.URL, p.Method)
 	if err == nil {
-		p.Add("oauth_signature", signature)
+		p.values.Set("oauth_signature", signature)
+		p.signHook(p.ConsumerKey)
 	}
 	return signature, err
 }
 
 // IsValid returns if lti request is valid, currently only checks
-// if signature is correct
+// if signature is correct. The timestamp is checked to be recent
+// only when ClockSkew or Nonces are set, and with Nonces replayed
+// requests are rejected too.
+// Launches received as GET are rejected, with ErrGETLaunch when
+// their signature is valid, unless AllowGET is set.
+//
+// The params of r are stored in p, so a Provider shared by
+// concurrent launches should use Verify instead.
+//
+// The errors returned wrap the Err* values of the package, and can
+// be checked with errors.Is.
 func (p *Provider) IsValid(r *http.Request) (bool, error) {
-	r.ParseForm()
-	p.values = r.Form
+	if errs := p.verify(r, false); len(errs) > 0 {
+		return false, errs[0]
+	}
+	return true, nil
+}
 
-	ckey := r.Form.Get("oauth_consumer_key")
-	if ckey != p.ConsumerKey {
-		return false, fmt.Errorf("Invalid consumer key provided")
+// Verify verifies the launch r as IsValid does, on a copy of p that
+// is returned holding the params of r, even when it fails. p is
+// left untouched, so it can verify concurrent launches.
+func (p *Provider) Verify(r *http.Request) (*Provider, error) {
+	c := p.Clone()
+	_, err := c.IsValid(r)
+	return c, err
+}
+
+// verify checks the request r, storing its params. The OAuth params
+// can be sent in the Authorization header too, signing a non form
+// body with oauth_body_hash. It stops at the
+// first failed check, unless all is set.
+func (p *Provider) verify(r *http.Request, all bool) (errs []error) {
+	var ckey string
+	defer func() {
+		for _, err := range errs {
+			p.debugf("lti: check failed: %s", err)
+		}
+		p.validationHooks(ckey, errs)
+	}()
+	form, err := requestParams(r, p.MaxFormSize)
+	if err != nil {
+		return []error{err}
 	}
+	p.SetParams(form)
+	defer p.applyPrivacy()
 
-	if r.Form.Get("oauth_signature_method") != p.Signer.GetMethod() {
-		return false, fmt.Errorf("wrong signature method %s",
-			r.Form.Get("oauth_signature_method"))
+	fail := func(err error) bool {
+		errs = append(errs, err)
+		return !all
 	}
-	signature := r.Form.Get("oauth_signature")
-	// log.Printf("REQuest URLS %s", r.RequestURI)
-	sig, err := Sign(r.Form, p.URL, r.Method, p.Signer)
+
+	ckey = form.Get("oauth_consumer_key")
+	verifiers, err := p.verifiers(ckey)
 	if err != nil {
-		return false, err
+		return append(errs, err)
 	}
-	if sig == signature {
-		return true, nil
+	// failures are recorded by key only for known consumers, the
+	// others are any key sent by anyone.
+	fkey := ckey
+	if len(verifiers) == 0 {
+		fkey = UnknownConsumerKey
+		p.recordFailure(fkey, FailureUnknownKey)
+		if fail(fmt.Errorf("%w provided", ErrInvalidConsumerKey)) {
+			return errs
+		}
+		verifiers = []oauth.OauthVerifier{p.verifier()}
+	} else if p.AllowPlaintext && form.Get("oauth_signature_method") == oauth.PLAINTEXT {
+		if verifiers, err = p.plaintextVerifiers(r, ckey); err != nil {
+			p.recordFailure(fkey, FailureBadSignature)
+			return append(errs, err)
+		}
 	}
-	return false, fmt.Errorf("Invalid signature, %s, expected %s", sig, signature)
+
+	if form.Get("oauth_signature_method") != verifiers[0].GetMethod() {
+		p.recordFailure(fkey, FailureBadSignature)
+		// without the right method the signature can't be checked
+		return append(errs, fmt.Errorf("%w %s", ErrInvalidSignatureMethod,
+			form.Get("oauth_signature_method")))
+	}
+	if err := p.checkOAuthVersion(form.Get("oauth_version")); err != nil {
+		if fail(err) {
+			return errs
+		}
+	}
+	signed, err := p.checkExtraOAuth(r, ckey, form)
+	if err != nil {
+		if fail(err) {
+			return errs
+		}
+	}
+
+	signature := form.Get("oauth_signature")
+	var base string
+	validSig := false
+urls:
+	for _, u := range p.launchURLs(r) {
+		// the params of r already hold its query
+		if base, err = p.baseString(signed, withoutQuery(u), r.Method); err != nil {
+			return append(errs, err)
+		}
+		for _, v := range verifiers {
+			if v.Verify(base, signature) == nil {
+				validSig = true
+				break urls
+			}
+		}
+	}
+	if strings.EqualFold(r.Method, "GET") && !p.AllowGET {
+		// LTI launches must be POSTed, a GET launch means the
+		// consumer is misconfigured, tell the admin what to fix.
+		if validSig {
+			return append(errs, ErrGETLaunch)
+		}
+		p.recordFailure(fkey, FailureBadSignature)
+		p.debugSignature(verifiers[0], base, signature)
+		return append(errs, fmt.Errorf("%w on a GET launch: "+
+			"the consumer should be configured to POST launches",
+			ErrInvalidSignature))
+	}
+	if !validSig {
+		p.recordFailure(fkey, FailureBadSignature)
+		p.debugSignature(verifiers[0], base, signature)
+		if fail(ErrInvalidSignature) {
+			return errs
+		}
+	}
+
+	if err := checkBodyHash(r, form.Get("oauth_signature_method"), form.Get("oauth_body_hash"), p.MaxFormSize); err != nil {
+		p.recordFailure(fkey, FailureBadSignature)
+		if fail(err) {
+			return errs
+		}
+	}
+
+	ts, err := p.checkTimestamp(form.Get("oauth_timestamp"))
+	if err != nil {
+		p.recordFailure(fkey, FailureStaleTimestamp)
+		if fail(err) {
+			return errs
+		}
+	}
+	// a nonce is only recorded for authentic requests, so forged
+	// ones can't burn the nonces of others.
+	if len(errs) == 0 {
+		if err := p.checkNonce(ckey, form.Get("oauth_nonce"), ts); err != nil {
+			if errors.Is(err, ErrReplayedNonce) {
+				p.recordFailure(fkey, FailureReplay)
+			}
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// checkOAuthVersion accepts a missing oauth_version, that means 1.0,
+// or 1.0, and 1.0a when AllowOAuth10a is set.
+func (p *Provider) checkOAuthVersion(v string) error {
+	switch {
+	case v == "" || v == oAuthVersion:
+		return nil
+	case v == "1.0a" && p.AllowOAuth10a:
+		return nil
+	}
+	return fmt.Errorf("%w %s", ErrUnsupportedOAuthVersion, v)
 }
 
 // SetSigner defines the signer that want to use.
@@ -176,7 +550,6 @@ func Sign(form url.Values, u, method string, firm oauth.OauthSigner) (string, er
 	if err != nil {
 		return "", err
 	}
-	// log.Printf("Base string: %s", str)
 	sig, err := firm.GetSignature(str)
 	if err != nil {
 		return "", err
@@ -184,23 +557,99 @@ func Sign(form url.Values, u, method string, firm oauth.OauthSigner) (string, er
 	return sig, nil
 }
 
+// sign signs form as Sign does, logging the base string and the
+// signature when debugging.
+func (p *Provider) sign(signer oauth.OauthSigner, form url.Values, u, method string) (string, error) {
+	str, err := p.baseString(form, u, method)
+	if err != nil {
+		return "", err
+	}
+	sig, err := signer.GetSignature(str)
+	p.debugf("lti: signature %s", sig)
+	return sig, err
+}
+
+// baseString returns the base string of form, normalized when
+// NormalizeUnicode is set, logging it when debugging.
+func (p *Provider) baseString(form url.Values, u, method string) (string, error) {
+	if p.NormalizeUnicode {
+		form = normalizeValues(form)
+	}
+	str, err := getBaseString(method, u, form)
+	if err == nil {
+		p.debugf("lti: %s base string %s", u, str)
+	}
+	return str, err
+}
+
+// debugSignature logs the signature expected by v for an invalid
+// signature when debugging. It's never part of the errors, as it
+// would be a valid signature for whoever reads them.
+func (p *Provider) debugSignature(v oauth.OauthVerifier, base, signature string) {
+	if !p.Debug {
+		return
+	}
+	s, ok := v.(oauth.OauthSigner)
+	if !ok || v.GetMethod() == oauth.PLAINTEXT {
+		// PLAINTEXT signatures are the secrets, keep them out
+		return
+	}
+	if sig, err := s.GetSignature(base); err == nil {
+		p.debugf("lti: invalid signature %s, expected %s", signature, sig)
+	}
+}
+
+// debugf logs when Debug is set.
+func (p *Provider) debugf(format string, v ...interface{}) {
+	if !p.Debug {
+		return
+	}
+	if p.Logger != nil {
+		p.Logger.Printf(format, v...)
+		return
+	}
+	log.Printf(format, v...)
+}
+
 func getBaseString(m, u string, form url.Values) (string, error) {
+	pu, err := url.Parse(u)
+	if err != nil {
+		return "", err
+	}
+	query := pu.Query()
+	pu.RawQuery, pu.Fragment = "", ""
 
-	var kv []oauth.KV
-	for k := range form {
+	// Every value of a repeated param is signed.
+	signed := make(url.Values, len(form)+len(query))
+	for k, vs := range form {
 		if k != "oauth_signature" {
-			s := oauth.KV{k, form.Get(k)}
-			kv = append(kv, s)
+			signed[k] = vs
 		}
 	}
+	// The query of the URL is part of the signed params, every pair
+	// of it, even when its names are in form too, as RFC 5849
+	// section 3.4.1.3 says.
+	for k, vs := range query {
+		signed[k] = append(append([]string(nil), signed[k]...), vs...)
+	}
 
-	str, err := oauth.GetBaseString(m, u, kv)
-	if err != nil {
-		return "", err
+	return oauth.GetBaseString(m, pu.String(), oauth.KVsFromValues(signed))
+}
+
+// nonce returns the nonce of a request, from NonceSource when set.
+func (p *Provider) nonce() (string, error) {
+	if p.NonceSource != nil {
+		return p.NonceSource.Nonce()
+	}
+	return nonce(), nil
+}
+
+// now returns the current time, from Clock when set.
+func (p *Provider) now() time.Time {
+	if p.Clock != nil {
+		return p.Clock.Now()
 	}
-	// ugly patch for formatting string as expected.
-	str = strings.Replace(str, "%2B", "%2520", -1)
-	return str, nil
+	return time.Now()
 }
 
 var nonceCounter uint64
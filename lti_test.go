@@ -1,11 +1,20 @@
 package lti
 
 import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"errors"
+	"io/ioutil"
 	"log"
 	"net/http"
 	"net/url"
+	"reflect"
+	"strconv"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/jordic/lti/oauth"
 )
@@ -111,7 +120,7 @@ func TestCurrentSigner(t *testing.T) {
 	if ok == true {
 		t.Error("Should fail because incorrect consumer key")
 	}
-	if !strings.Contains(err.Error(), "wrong signature") {
+	if !errors.Is(err, ErrInvalidSignatureMethod) {
 		t.Error("Should contain error in consumer type")
 	}
 
@@ -198,3 +207,379 @@ func GenerateForm() url.Values {
 }
 
 var OT = "POST&http%3A%2F%2Fwww.imsglobal.org%2Fdevelopers%2FLTI%2Ftest%2Fv1p1%2Ftool.php&context_id%3D456434513%26context_label%3DSI182%26context_title%3DDesign%2520of%2520Personal%2520Environments%26launch_presentation_css_url%3Dhttp%253A%252F%252Fwww.imsglobal.org%252Fdevelopers%252FLTI%252Ftest%252Fv1p1%252Flms.css%26launch_presentation_document_target%3Dframe%26launch_presentation_locale%3Den-US%26launch_presentation_return_url%3Dhttp%253A%252F%252Fwww.imsglobal.org%252Fdevelopers%252FLTI%252Ftest%252Fv1p1%252Flms_return.php%26lis_outcome_service_url%3Dhttp%253A%252F%252Fwww.imsglobal.org%252Fdevelopers%252FLTI%252Ftest%252Fv1p1%252Fcommon%252Ftool_consumer_outcome.php%253Fb64%253DMTIzNDU6OjpzZWNyZXQ%253D%26lis_person_contact_email_primary%3Duser%2540school.edu%26lis_person_name_family%3DPublic%26lis_person_name_full%3DJane%2520Q.%2520Public%26lis_person_name_given%3DGiven%26lis_person_sourcedid%3Dschool.edu%253Auser%26lis_result_sourcedid%3Dfeb-123-456-2929%253A%253A28883%26lti_message_type%3Dbasic-lti-launch-request%26lti_version%3DLTI-1p0%26oauth_callback%3Dabout%253Ablank%26oauth_consumer_key%3D12345%26oauth_nonce%3D93ac608e18a7d41dec8f7219e1bf6a17%26oauth_signature_method%3DHMAC-SHA1%26oauth_timestamp%3D1348093590%26oauth_version%3D1.0%26resource_link_description%3DA%2520weekly%2520blog.%26resource_link_id%3D120988f929-274612%26resource_link_title%3DWeekly%2520Blog%26roles%3DInstructor%26tool_consumer_info_product_family_code%3Dims%26tool_consumer_info_version%3D1.1%26tool_consumer_instance_description%3DUniversity%2520of%2520School%2520%2528LMSng%2529%26tool_consumer_instance_guid%3Dlmsng.school.edu%26user_id%3D292832126"
+
+func TestGETLaunch(t *testing.T) {
+	p := NewProvider("asdf", "http://urltest.com/")
+	p.ConsumerKey = "12345"
+	p.Method = "GET"
+	p.Add("resource_link_id", "1086")
+	p.Sign()
+
+	u, _ := url.Parse("http://urltest.com/?" + p.Params().Encode())
+	r := &http.Request{Method: "GET", URL: u}
+
+	pp := NewProvider("asdf", "http://urltest.com/")
+	pp.ConsumerKey = "12345"
+	ok, err := pp.IsValid(r)
+	if ok || err != ErrGETLaunch {
+		t.Errorf("GET launch should fail with ErrGETLaunch, got %v", err)
+	}
+
+	u, _ = url.Parse("http://urltest.com/?" + p.Params().Encode() + "&tampered=1")
+	r = &http.Request{Method: "GET", URL: u}
+	ok, err = pp.IsValid(r)
+	if ok || err == ErrGETLaunch || !strings.Contains(err.Error(), "POST") {
+		t.Errorf("Tampered GET launch should fail with invalid signature, got %v", err)
+	}
+}
+
+func TestConcurrentIsValid(t *testing.T) {
+	p := NewProvider("asdf", "http://urltest.com/")
+	p.ConsumerKey = "12345"
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			c := NewProvider("asdf", "http://urltest.com/")
+			c.ConsumerKey = "12345"
+			c.Add("resource_link_id", strconv.Itoa(i))
+			c.Sign()
+			r := &http.Request{Method: "POST", Form: c.Params()}
+			l, err := p.Verify(r)
+			if err != nil {
+				t.Errorf("Request should be valid %s", err)
+			}
+			if id := l.Get("resource_link_id"); id != strconv.Itoa(i) {
+				t.Errorf("Launch %d should keep its own params, got %s", i, id)
+			}
+		}(i)
+	}
+	wg.Wait()
+	if !p.Empty("resource_link_id") {
+		t.Error("Verify should not store the params in the shared provider")
+	}
+}
+
+func TestAllowGETLaunch(t *testing.T) {
+	c := NewProvider("asdf", "http://urltest.com/launch",
+		WithConsumerKey("12345"), WithMethod("get"))
+	c.Add("resource_link_id", "1086")
+	c.Sign()
+
+	r, _ := http.NewRequest("GET", "http://urltest.com/launch?"+c.Params().Encode(), nil)
+	p := NewProvider("asdf", "http://urltest.com/launch",
+		WithConsumerKey("12345"), WithAllowGET())
+	if ok, err := p.IsValid(r); !ok {
+		t.Errorf("GET launch should be valid, got %s", err)
+	}
+	if p.Get("resource_link_id") != "1086" {
+		t.Error("Params should be collected from the query")
+	}
+}
+
+func TestBaseStringURLQuery(t *testing.T) {
+	v := url.Values{}
+	v.Set("a", "1")
+	str, err := getBaseString("POST", "http://urltest.com/launch?b=2#frag", v)
+	if err != nil {
+		t.Fatalf("Error generating base string %s", err)
+	}
+	if str != "POST&http%3A%2F%2Furltest.com%2Flaunch&a%3D1%26b%3D2" {
+		t.Errorf("Query should be signed as params, got %s", str)
+	}
+	v.Set("b", "3")
+	if str, _ = getBaseString("POST", "http://urltest.com/launch?b=2", v); str !=
+		"POST&http%3A%2F%2Furltest.com%2Flaunch&a%3D1%26b%3D2%26b%3D3" {
+		t.Errorf("Params in query and form should be signed, got %s", str)
+	}
+
+	c := NewProvider("asdf", "http://urltest.com/launch?b=2", WithConsumerKey("12345"))
+	c.Add("a", "1")
+	c.Sign()
+
+	r, _ := http.NewRequest("POST", "http://urltest.com/launch?b=2", strings.NewReader(c.Params().Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	p := NewProvider("asdf", "http://urltest.com/launch?b=2", WithConsumerKey("12345"))
+	if ok, err := p.IsValid(r); !ok {
+		t.Errorf("Launch to an URL with query should be valid, got %s", err)
+	}
+}
+
+func TestBaseStringEncoding(t *testing.T) {
+	v := url.Values{}
+	v.Set("name", "a+b c")
+	str, err := getBaseString("POST", "http://urltest.com/", v)
+	if err != nil {
+		t.Fatalf("Error generating base string %s", err)
+	}
+	if str != "POST&http%3A%2F%2Furltest.com%2F&name%3Da%252Bb%2520c" {
+		t.Errorf("Plus signs and spaces should be encoded, got %s", str)
+	}
+}
+
+func TestMultiValuedParams(t *testing.T) {
+	v := url.Values{}
+	v.Add("b", "2")
+	v.Add("a", "z")
+	v.Add("a", "y")
+	str, err := getBaseString("POST", "http://urltest.com/", v)
+	if err != nil {
+		t.Fatalf("Error generating base string %s", err)
+	}
+	if str != "POST&http%3A%2F%2Furltest.com%2F&a%3Dy%26a%3Dz%26b%3D2" {
+		t.Errorf("Every value should be signed, sorted, got %s", str)
+	}
+
+	c := NewProvider("asdf", "http://urltest.com/", WithConsumerKey("12345"))
+	c.SetParams(url.Values{"custom_tag": {"one", "two"}})
+	c.Sign()
+
+	r, _ := http.NewRequest("POST", "http://urltest.com/", strings.NewReader(c.Params().Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	p := NewProvider("asdf", "http://urltest.com/", WithConsumerKey("12345"))
+	if ok, err := p.IsValid(r); !ok {
+		t.Errorf("Launch with repeated params should be valid, got %s", err)
+	}
+
+	r, _ = http.NewRequest("POST", "http://urltest.com/",
+		strings.NewReader(c.Params().Encode()+"&custom_tag=three"))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if ok, _ := p.IsValid(r); ok {
+		t.Error("Launch with an added repeated param should fail")
+	}
+}
+
+func TestClone(t *testing.T) {
+	p := NewProvider("asdf", "http://urltest.com/",
+		WithConsumerKey("12345"),
+		WithURLPolicy(URLIgnoreTrailingSlash),
+		WithDetectURL(),
+		WithTrustProxy(),
+		WithAllowGET(),
+		WithAllowOAuth10a(),
+		WithNonceStore(NewMemoryNonceStore(time.Minute)),
+		WithFailureStore(NewMemoryFailureStore()),
+		WithMaxFormSize(1<<20),
+		WithPrivacyHash([]byte("key")),
+		WithTokenKey([]byte("token key")),
+		WithExtraOAuthPolicy(ExtraOAuthIgnore),
+		WithLenientVersion(),
+		WithClockSkew(time.Minute),
+		WithStrictMode(),
+		WithSecretStore(StaticSecrets{"12345": {"asdf"}}),
+		WithUnicodeNormalization(),
+		WithAllowPlaintext(),
+		WithVerifier(oauth.GetHMACSigner("asdf", "")),
+		WithNonceSource(fixedNonce("abc")),
+		WithClock(fixedClock(time.Unix(1348093590, 0))),
+		WithDebug(log.New(ioutil.Discard, "", 0)),
+	)
+	p.Hooks = &Hooks{}
+	p.ExtraOAuthByConsumer = map[string]ExtraOAuthPolicy{"other": ExtraOAuthReject}
+	p.AltURLs = []string{"http://alt.urltest.com/"}
+	p.LaunchURLs = func(r *http.Request) []string { return nil }
+	p.Redaction = &RedactionPolicy{Fields: []string{"a"}}
+	p.SetParams(GenerateForm())
+
+	c := p.Clone()
+	pv, cv := reflect.ValueOf(p).Elem(), reflect.ValueOf(c).Elem()
+	for i := 0; i < pv.NumField(); i++ {
+		f := pv.Type().Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		if pv.Field(i).IsZero() {
+			t.Errorf("Field %s should be set by the test", f.Name)
+		}
+		if f.Type.Kind() == reflect.Func {
+			if pv.Field(i).Pointer() != cv.Field(i).Pointer() {
+				t.Errorf("Field %s not cloned", f.Name)
+			}
+			continue
+		}
+		if !reflect.DeepEqual(pv.Field(i).Interface(), cv.Field(i).Interface()) {
+			t.Errorf("Field %s not cloned", f.Name)
+		}
+	}
+
+	if !reflect.DeepEqual(c.Params(), p.Params()) {
+		t.Error("Params should be cloned")
+	}
+	c.Add("user_id", "other")
+	c.Params()["roles"][0] = "Learner"
+	if p.Get("user_id") == "other" || p.Get("roles") == "Learner" {
+		t.Error("Params of the clone should not be shared")
+	}
+}
+
+func TestDebugLogger(t *testing.T) {
+	var b strings.Builder
+	p := NewProvider("asdf", "http://urltest.com/", WithConsumerKey("12345"),
+		WithDebug(log.New(&b, "", 0)))
+
+	r := signedRequest(t, "http://urltest.com/")
+	sig := r.Form.Get("oauth_signature")
+	r.Form.Set("oauth_signature", "forged")
+	ok, err := p.IsValid(r)
+	if ok {
+		t.Fatal("Forged request should be invalid")
+	}
+	if strings.Contains(err.Error(), sig) {
+		t.Errorf("Error should not contain the expected signature, got %s", err)
+	}
+	for _, s := range []string{"base string POST&http%3A%2F%2Furltest.com%2F&",
+		"check failed: invalid signature", "invalid signature forged, expected " + sig} {
+		if !strings.Contains(b.String(), s) {
+			t.Errorf("Debug output should contain %q\n%s", s, b.String())
+		}
+	}
+
+	b.Reset()
+	p.Debug = false
+	p.IsValid(signedRequest(t, "http://urltest.com/"))
+	if b.Len() > 0 {
+		t.Errorf("Nothing should be logged without Debug, got %s", b.String())
+	}
+}
+
+func TestParamsAPI(t *testing.T) {
+	p := &Provider{}
+	if p.Has("a") {
+		t.Error("Empty provider should have no params")
+	}
+	p.Del("a").
+		AddAll(url.Values{"a": {"1", "2"}, "b": {""}}).
+		Merge(map[string]string{"c": "3", "a": "4"})
+	if !p.Has("b") || !p.Empty("b") || p.Get("a") != "4" || p.Get("c") != "3" {
+		t.Errorf("Unexpected params %v", p.Params())
+	}
+	p.AddAll(url.Values{"a": {"5", "6"}})
+	if vs := p.Params()["a"]; len(vs) != 2 || vs[1] != "6" {
+		t.Errorf("AddAll should replace the values, got %v", vs)
+	}
+	p.Del("a")
+	if p.Has("a") {
+		t.Error("Param should be deleted")
+	}
+}
+
+func TestHMACSHA256Launch(t *testing.T) {
+	c := NewProvider("asdf", "http://urltest.com/", WithConsumerKey("12345"),
+		WithSigner(oauth.GetHMAC256Signer("asdf", "")))
+	c.Add("resource_link_id", "1")
+	c.Sign()
+	if c.Get("oauth_signature_method") != "HMAC-SHA256" {
+		t.Errorf("Wrong signature method %s", c.Get("oauth_signature_method"))
+	}
+
+	p := NewProvider("asdf", "http://urltest.com/", WithConsumerKey("12345"),
+		WithSigner(oauth.GetHMAC256Signer("asdf", "")))
+	if ok, err := p.IsValid(&http.Request{Method: "POST", Form: c.Params()}); !ok {
+		t.Errorf("HMAC-SHA256 launch should be valid, got %s", err)
+	}
+}
+
+func TestPlaintextLaunch(t *testing.T) {
+	launch := func(u, secret string) *http.Request {
+		c := NewProvider(secret, u, WithConsumerKey("12345"),
+			WithSigner(oauth.GetPlaintextSigner(secret, "")))
+		c.Add("resource_link_id", "1")
+		c.Sign()
+		r, _ := http.NewRequest("POST", u, strings.NewReader(c.Params().Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if r.URL.Scheme == "https" {
+			r.TLS = &tls.ConnectionState{}
+		}
+		return r
+	}
+
+	p := NewProvider("as df", "https://urltest.com/", WithConsumerKey("12345"))
+	if _, err := p.IsValid(launch("https://urltest.com/", "as df")); !errors.Is(err, ErrInvalidSignatureMethod) {
+		t.Errorf("PLAINTEXT should be rejected by default, got %v", err)
+	}
+
+	p.AllowPlaintext = true
+	if ok, err := p.IsValid(launch("https://urltest.com/", "as df")); !ok {
+		t.Errorf("PLAINTEXT launch over https should be valid, got %s", err)
+	}
+	_, err := p.IsValid(launch("https://urltest.com/", "other"))
+	if !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("PLAINTEXT launch with other secret should fail, got %v", err)
+	}
+	if strings.Contains(err.Error(), "as%20df") {
+		t.Errorf("Secret should not be in the error %s", err)
+	}
+	p.URL = "http://urltest.com/"
+	if _, err := p.IsValid(launch("http://urltest.com/", "as df")); !errors.Is(err, ErrInvalidSignatureMethod) {
+		t.Errorf("PLAINTEXT launch over http should fail, got %v", err)
+	}
+
+	// X-Forwarded-Proto is sent by anyone, unless behind a proxy
+	r := launch("http://urltest.com/", "as df")
+	r.Header.Set("X-Forwarded-Proto", "https")
+	if _, err := p.IsValid(r); !errors.Is(err, ErrInvalidSignatureMethod) {
+		t.Errorf("PLAINTEXT launch over http claiming https should fail, got %v", err)
+	}
+	p.TrustProxy = true
+	r = launch("http://urltest.com/", "as df")
+	r.Header.Set("X-Forwarded-Proto", "https")
+	if ok, err := p.IsValid(r); !ok {
+		t.Errorf("PLAINTEXT launch over https behind a proxy should be valid, got %s", err)
+	}
+}
+
+func TestRSALaunch(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := NewProvider("", "http://urltest.com/", WithConsumerKey("12345"),
+		WithSigner(oauth.GetRSASigner(key)))
+	c.Add("resource_link_id", "1")
+	c.Sign()
+
+	p := NewProvider("", "http://urltest.com/", WithConsumerKey("12345"),
+		WithVerifier(oauth.GetRSAVerifier(&key.PublicKey)))
+	if ok, err := p.IsValid(&http.Request{Method: "POST", Form: c.Params()}); !ok {
+		t.Errorf("RSA-SHA1 launch should be valid, got %s", err)
+	}
+	c.Add("resource_link_id", "2")
+	_, err = p.IsValid(&http.Request{Method: "POST", Form: c.Params()})
+	if !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("Tampered RSA-SHA1 launch should fail, got %v", err)
+	}
+}
+
+type fixedClock time.Time
+
+func (c fixedClock) Now() time.Time { return time.Time(c) }
+
+type fixedNonce string
+
+func (n fixedNonce) Nonce() (string, error) { return string(n), nil }
+
+func TestDeterministicSign(t *testing.T) {
+	sign := func() url.Values {
+		c := NewProvider("asdf", "http://urltest.com/", WithConsumerKey("12345"),
+			WithClock(fixedClock(time.Unix(1348093590, 0))),
+			WithNonceSource(fixedNonce("abc")))
+		c.Add("resource_link_id", "1")
+		c.Sign()
+		return c.Params()
+	}
+	v := sign()
+	if v.Get("oauth_timestamp") != "1348093590" || v.Get("oauth_nonce") != "abc" {
+		t.Errorf("Wrong timestamp or nonce %v", v)
+	}
+	if sig := sign().Get("oauth_signature"); sig != v.Get("oauth_signature") {
+		t.Errorf("Signatures should be the same, got %s and %s", sig, v.Get("oauth_signature"))
+	}
+
+	p := NewProvider("asdf", "http://urltest.com/", WithConsumerKey("12345"),
+		WithClock(fixedClock(time.Unix(1348093590, 0))))
+	if ok, err := p.IsValid(&http.Request{Method: "POST", Form: v}); !ok {
+		t.Errorf("Launch should be valid at the time of the clock, got %s", err)
+	}
+}
@@ -0,0 +1,501 @@
+// Package ltitest provides test doubles for the interfaces used
+// by the lti package, so tests of applications built on top of it
+// don't need to write their own mocks.
+package ltitest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jordic/lti"
+	"github.com/jordic/lti/oauth"
+)
+
+// NoopSigner is an oauth.OauthSigner that always returns the
+// same Signature, whatever the base string.
+type NoopSigner struct {
+	Method    string
+	Signature string
+}
+
+var _ oauth.OauthSigner = (*NoopSigner)(nil)
+
+// GetSignature returns the configured signature.
+func (s *NoopSigner) GetSignature(baseString string) (string, error) {
+	return s.Signature, nil
+}
+
+// GetMethod returns the configured method, HMAC-SHA1 if empty.
+func (s *NoopSigner) GetMethod() string {
+	if s.Method == "" {
+		return "HMAC-SHA1"
+	}
+	return s.Method
+}
+
+// RecordingSigner wraps an oauth.OauthSigner and records every
+// base string it is asked to sign.
+//
+//  s := &ltitest.RecordingSigner{Signer: oauth.GetHMACSigner("secret", "")}
+//  p.SetSigner(s)
+//  p.Sign()
+//  s.BaseStrings() // contains the base string signed
+type RecordingSigner struct {
+	Signer oauth.OauthSigner
+
+	mu    sync.Mutex
+	calls []string
+}
+
+var _ oauth.OauthSigner = (*RecordingSigner)(nil)
+
+// GetSignature records the base string and delegates to Signer.
+func (s *RecordingSigner) GetSignature(baseString string) (string, error) {
+	s.mu.Lock()
+	s.calls = append(s.calls, baseString)
+	s.mu.Unlock()
+	return s.Signer.GetSignature(baseString)
+}
+
+// GetMethod delegates to Signer.
+func (s *RecordingSigner) GetMethod() string {
+	return s.Signer.GetMethod()
+}
+
+// BaseStrings returns the base strings signed so far.
+func (s *RecordingSigner) BaseStrings() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.calls...)
+}
+
+// NonceStore is a lti.NonceStore double. With Replay set, every
+// nonce is reported as seen, otherwise none is. Calls are recorded.
+type NonceStore struct {
+	Replay bool
+
+	mu     sync.Mutex
+	nonces []string
+}
+
+// Seen records the nonce and returns Replay.
+func (s *NonceStore) Seen(consumerKey, nonce string, ts time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nonces = append(s.nonces, nonce)
+	return s.Replay, nil
+}
+
+// Nonces returns the nonces checked so far.
+func (s *NonceStore) Nonces() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.nonces...)
+}
+
+// Failure is a failure recorded by FailureStore.
+type Failure struct {
+	ConsumerKey string
+	Kind        lti.FailureKind
+}
+
+// FailureStore is a lti.FailureStore double recording every failure.
+// With Err set, every call fails with it.
+type FailureStore struct {
+	Err error
+
+	mu       sync.Mutex
+	failures []Failure
+}
+
+var _ lti.FailureStore = (*FailureStore)(nil)
+
+// RecordFailure records the failure, unless Err is set.
+func (s *FailureStore) RecordFailure(consumerKey string, kind lti.FailureKind) error {
+	if s.Err != nil {
+		return s.Err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures = append(s.failures, Failure{consumerKey, kind})
+	return nil
+}
+
+// FailureCounts returns the counters of the failures recorded.
+func (s *FailureStore) FailureCounts() (map[string]map[lti.FailureKind]int64, error) {
+	if s.Err != nil {
+		return nil, s.Err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	counts := map[string]map[lti.FailureKind]int64{}
+	for _, f := range s.failures {
+		if counts[f.ConsumerKey] == nil {
+			counts[f.ConsumerKey] = map[lti.FailureKind]int64{}
+		}
+		counts[f.ConsumerKey][f.Kind]++
+	}
+	return counts, nil
+}
+
+// Failures returns the failures recorded so far, in order.
+func (s *FailureStore) Failures() []Failure {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Failure(nil), s.failures...)
+}
+
+// SecretStore is a lti.SecretStore double returning the secrets of
+// ByKey, and recording the keys asked. With Err set, every call
+// fails with it.
+type SecretStore struct {
+	ByKey map[string][]string
+	Err   error
+
+	mu    sync.Mutex
+	asked []string
+}
+
+var _ lti.SecretStore = (*SecretStore)(nil)
+
+// Secrets records the key and returns its secrets.
+func (s *SecretStore) Secrets(consumerKey string) ([]string, error) {
+	s.mu.Lock()
+	s.asked = append(s.asked, consumerKey)
+	s.mu.Unlock()
+	if s.Err != nil {
+		return nil, s.Err
+	}
+	return s.ByKey[consumerKey], nil
+}
+
+// Asked returns the consumer keys asked so far.
+func (s *SecretStore) Asked() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.asked...)
+}
+
+// LaunchStore is a lti.LaunchStore double keeping the launches in
+// memory. With Err set, every call fails with it.
+type LaunchStore struct {
+	Err error
+
+	mu       sync.Mutex
+	launches map[string]lti.Launch
+}
+
+var _ lti.LaunchStore = (*LaunchStore)(nil)
+
+// SaveLaunch keeps a copy of l, unless Err is set.
+func (s *LaunchStore) SaveLaunch(ctx context.Context, l *lti.Launch) error {
+	if s.Err != nil {
+		return s.Err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.launches == nil {
+		s.launches = map[string]lti.Launch{}
+	}
+	s.launches[l.ID] = *l
+	return nil
+}
+
+// LoadLaunch returns the launch saved with id, or
+// lti.ErrLaunchNotFound.
+func (s *LaunchStore) LoadLaunch(ctx context.Context, id string) (*lti.Launch, error) {
+	if s.Err != nil {
+		return nil, s.Err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	l, ok := s.launches[id]
+	if !ok {
+		return nil, lti.ErrLaunchNotFound
+	}
+	return &l, nil
+}
+
+// LinkStore is a lti.LinkStore double keeping the links saved, in
+// order. With Err set, every call fails with it.
+type LinkStore struct {
+	Err error
+
+	mu    sync.Mutex
+	saved []lti.Link
+}
+
+var _ lti.LinkStore = (*LinkStore)(nil)
+
+// SaveLink records l, unless Err is set.
+func (s *LinkStore) SaveLink(ctx context.Context, l lti.Link) error {
+	if s.Err != nil {
+		return s.Err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.saved = append(s.saved, l)
+	return nil
+}
+
+// Links returns the links saved for the activity, the last one
+// saved of each consumer key, resource link id and user id.
+func (s *LinkStore) Links(ctx context.Context, activityID string) ([]lti.Link, error) {
+	if s.Err != nil {
+		return nil, s.Err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var res []lti.Link
+	seen := map[[3]string]bool{}
+	for i := len(s.saved) - 1; i >= 0; i-- {
+		l := s.saved[i]
+		k := [3]string{l.ConsumerKey, l.ResourceLinkID, l.UserID}
+		if seen[k] {
+			continue
+		}
+		seen[k] = true
+		if l.ActivityID == activityID {
+			res = append(res, l)
+		}
+	}
+	return res, nil
+}
+
+// Saved returns every link saved so far, in order.
+func (s *LinkStore) Saved() []lti.Link {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]lti.Link(nil), s.saved...)
+}
+
+// Authorizer is a lti.Authorizer double answering Err to every
+// request, and counting them.
+type Authorizer struct {
+	Err error
+
+	mu    sync.Mutex
+	calls int
+}
+
+var _ lti.Authorizer = (*Authorizer)(nil)
+
+// Authorize counts the request and returns Err.
+func (a *Authorizer) Authorize(r *http.Request) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.calls++
+	return a.Err
+}
+
+// Calls returns the number of requests authorized so far.
+func (a *Authorizer) Calls() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.calls
+}
+
+// FieldEncrypter is a lti.FieldEncrypter double that "encrypts" by
+// prefixing the value with "enc:" and the field name, so tests can
+// see what was encrypted. With Err set, every call fails with it.
+type FieldEncrypter struct {
+	Err error
+}
+
+var _ lti.FieldEncrypter = (*FieldEncrypter)(nil)
+
+// Encrypt returns "enc:field:value".
+func (e *FieldEncrypter) Encrypt(field, value string) (string, error) {
+	if e.Err != nil {
+		return "", e.Err
+	}
+	return "enc:" + field + ":" + value, nil
+}
+
+// ErrNotEncrypted is returned by FieldEncrypter.Decrypt for the
+// values it didn't encrypt for the field.
+var ErrNotEncrypted = errors.New("ltitest: value not encrypted")
+
+// Decrypt reverses Encrypt.
+func (e *FieldEncrypter) Decrypt(field, value string) (string, error) {
+	if e.Err != nil {
+		return "", e.Err
+	}
+	prefix := "enc:" + field + ":"
+	if !strings.HasPrefix(value, prefix) {
+		return "", ErrNotEncrypted
+	}
+	return strings.TrimPrefix(value, prefix), nil
+}
+
+// LaunchVerifier is a lti.LaunchVerifier double accepting every
+// launch as one holding Params, or failing with Err, for handlers
+// taking a LaunchVerifier, like lti.ToolSet. The requests are
+// recorded.
+type LaunchVerifier struct {
+	Params url.Values
+	Err    error
+
+	mu       sync.Mutex
+	requests []*http.Request
+}
+
+var _ lti.LaunchVerifier = (*LaunchVerifier)(nil)
+
+// Verify records r and returns a provider holding a copy of Params,
+// with Err.
+func (v *LaunchVerifier) Verify(r *http.Request) (*lti.Provider, error) {
+	v.mu.Lock()
+	v.requests = append(v.requests, r)
+	v.mu.Unlock()
+	p := lti.NewProvider("", "")
+	p.AddAll(v.Params)
+	return p, v.Err
+}
+
+// Requests returns the requests verified so far.
+func (v *LaunchVerifier) Requests() []*http.Request {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return append([]*http.Request(nil), v.requests...)
+}
+
+// RequestSigner is a lti.RequestSigner double that records the
+// requests and signs them with the Authorization header
+// "OAuth test", or fails with Err.
+type RequestSigner struct {
+	Err error
+
+	mu       sync.Mutex
+	requests []*http.Request
+}
+
+var _ lti.RequestSigner = (*RequestSigner)(nil)
+
+// SignRequest records r and sets its Authorization header, unless
+// Err is set.
+func (s *RequestSigner) SignRequest(r *http.Request) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = append(s.requests, r)
+	if s.Err != nil {
+		return s.Err
+	}
+	r.Header.Set("Authorization", "OAuth test")
+	return nil
+}
+
+// Requests returns the requests signed so far.
+func (s *RequestSigner) Requests() []*http.Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*http.Request(nil), s.requests...)
+}
+
+// Logger is a lti.Logger double keeping the lines logged, to check
+// the debug output of a Provider:
+//
+//  l := &ltitest.Logger{}
+//  p := lti.NewProvider("secret", "http://localhost/", lti.WithDebug(l))
+type Logger struct {
+	mu    sync.Mutex
+	lines []string
+}
+
+var _ lti.Logger = (*Logger)(nil)
+
+// Printf records the line formatted.
+func (l *Logger) Printf(format string, v ...interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.lines = append(l.lines, fmt.Sprintf(format, v...))
+}
+
+// Lines returns the lines logged so far.
+func (l *Logger) Lines() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]string(nil), l.lines...)
+}
+
+// Clock is an oauth.Clock double whose time only moves when told,
+// starting at the zero time, or at the time of NewClock.
+type Clock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+// NewClock returns a Clock set at now.
+func NewClock(now time.Time) *Clock {
+	return &Clock{now: now}
+}
+
+// Now returns the time of the clock.
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Advance moves the clock by d, returning the new time.
+func (c *Clock) Advance(d time.Duration) time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	return c.now
+}
+
+// SoakStats is the result of SoakNonceStore.
+type SoakStats struct {
+	// Launches is the number of launches simulated.
+	Launches int
+	// MaxEntries is the peak of nonces kept by the store, and
+	// Final its state after the last launch. They are only set for
+	// the stores implementing lti.NonceStatsReporter.
+	MaxEntries int
+	Final      lti.NonceStats
+}
+
+// SoakNonceStore simulates n launches against s, one every interval
+// of the time of clock, each with its own nonce, failing if a fresh
+// nonce is reported as seen. The store should take its time from
+// clock, so its evictions follow the launches. With the peak of
+// nonces kept it can be checked the evictions bound the memory of
+// the store:
+//
+//  clock := ltitest.NewClock(time.Now())
+//  s := lti.NewMemoryNonceStore(5 * time.Minute)
+//  s.Clock = clock
+//  st, err := ltitest.SoakNonceStore(s, clock, 1000000, time.Millisecond)
+//  // st.MaxEntries stays under 2 * 5m / 1ms
+func SoakNonceStore(s lti.NonceStore, clock *Clock, n int, interval time.Duration) (SoakStats, error) {
+	reporter, _ := s.(lti.NonceStatsReporter)
+	st := SoakStats{Launches: n}
+	for i := 0; i < n; i++ {
+		now := clock.Advance(interval)
+		seen, err := s.Seen("soak", strconv.Itoa(i), now)
+		if err != nil {
+			return st, err
+		}
+		if seen {
+			return st, fmt.Errorf("ltitest: fresh nonce %d reported as seen", i)
+		}
+		if reporter == nil {
+			continue
+		}
+		if e := reporter.Stats().Entries; e > st.MaxEntries {
+			st.MaxEntries = e
+		}
+	}
+	if reporter != nil {
+		st.Final = reporter.Stats()
+	}
+	return st, nil
+}
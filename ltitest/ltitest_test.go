@@ -0,0 +1,255 @@
+package ltitest
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jordic/lti"
+	"github.com/jordic/lti/oauth"
+)
+
+func TestRecordingSigner(t *testing.T) {
+	s := &RecordingSigner{Signer: oauth.GetHMACSigner("secret", "")}
+	p := lti.NewProvider("secret", "http://localhost/")
+	p.Add("resource_link_id", "1")
+	p.SetSigner(s)
+
+	sig, err := p.Sign()
+	if err != nil {
+		t.Fatalf("Error signing %s", err)
+	}
+	if len(s.BaseStrings()) != 1 {
+		t.Errorf("Signer should record one call, got %d", len(s.BaseStrings()))
+	}
+	if s.GetMethod() != lti.SigHMAC {
+		t.Errorf("Method should be delegated, got %s", s.GetMethod())
+	}
+	if sig == "" {
+		t.Error("Request should be signed")
+	}
+}
+
+func TestNoopSigner(t *testing.T) {
+	s := &NoopSigner{Signature: "sig"}
+	got, _ := s.GetSignature("anything")
+	if got != "sig" || s.GetMethod() != "HMAC-SHA1" {
+		t.Errorf("Unexpected noop signer output %s %s", got, s.GetMethod())
+	}
+}
+
+func TestNonceStore(t *testing.T) {
+	s := &NonceStore{Replay: true}
+	var _ lti.NonceStore = s
+
+	c := lti.NewProvider("secret", "http://localhost/", lti.WithConsumerKey("key"))
+	c.Sign()
+	r := &http.Request{Method: "POST", Form: c.Params()}
+
+	p := lti.NewProvider("secret", "http://localhost/",
+		lti.WithConsumerKey("key"), lti.WithNonceStore(s))
+	if _, err := p.IsValid(r); !errors.Is(err, lti.ErrReplayedNonce) {
+		t.Errorf("Request should be rejected as replayed, got %v", err)
+	}
+	if n := s.Nonces(); len(n) != 1 || n[0] != c.Get("oauth_nonce") {
+		t.Errorf("Nonce should be recorded, got %v", n)
+	}
+}
+
+func signedLaunch(key string) *http.Request {
+	c := lti.NewProvider("secret", "http://localhost/", lti.WithConsumerKey(key))
+	c.Add("resource_link_id", "1")
+	c.Sign()
+	return &http.Request{Method: "POST", Form: c.Params()}
+}
+
+func TestFailureStore(t *testing.T) {
+	s := &FailureStore{}
+	p := lti.NewProvider("other", "http://localhost/",
+		lti.WithConsumerKey("key"), lti.WithFailureStore(s))
+	p.IsValid(signedLaunch("key"))
+
+	if f := s.Failures(); len(f) != 1 || f[0] != (Failure{"key", lti.FailureBadSignature}) {
+		t.Errorf("Bad signature should be recorded, got %v", f)
+	}
+	if counts, _ := s.FailureCounts(); counts["key"][lti.FailureBadSignature] != 1 {
+		t.Errorf("Unexpected counts %v", counts)
+	}
+	s.Err = errors.New("down")
+	if _, err := s.FailureCounts(); err != s.Err {
+		t.Errorf("Err should be returned, got %v", err)
+	}
+}
+
+func TestSecretStore(t *testing.T) {
+	s := &SecretStore{ByKey: map[string][]string{"key": {"old", "secret"}}}
+	p := lti.NewProvider("", "http://localhost/", lti.WithSecretStore(s))
+	if ok, err := p.IsValid(signedLaunch("key")); !ok {
+		t.Errorf("Launch signed with a secret of the store should be valid, got %s", err)
+	}
+	if a := s.Asked(); len(a) != 1 || a[0] != "key" {
+		t.Errorf("Consumer key should be recorded, got %v", a)
+	}
+	s.Err = errors.New("down")
+	if _, err := p.IsValid(signedLaunch("key")); !errors.Is(err, s.Err) {
+		t.Errorf("Store errors should be returned, got %v", err)
+	}
+}
+
+func TestLaunchStore(t *testing.T) {
+	ctx := context.Background()
+	s := &LaunchStore{}
+	if _, err := s.LoadLaunch(ctx, "1"); err != lti.ErrLaunchNotFound {
+		t.Errorf("Expected ErrLaunchNotFound, got %v", err)
+	}
+	s.SaveLaunch(ctx, &lti.Launch{ID: "1", UserID: "u1"})
+	if l, err := s.LoadLaunch(ctx, "1"); err != nil || l.UserID != "u1" {
+		t.Errorf("Launch should be loaded, got %v %v", l, err)
+	}
+	s.Err = errors.New("down")
+	if err := s.SaveLaunch(ctx, &lti.Launch{ID: "2"}); err != s.Err {
+		t.Errorf("Err should be returned, got %v", err)
+	}
+}
+
+func TestLinkStore(t *testing.T) {
+	ctx := context.Background()
+	s := &LinkStore{}
+	l := lti.Link{ActivityID: "quiz-1", ConsumerKey: "key", ResourceLinkID: "1", UserID: "u1"}
+	s.SaveLink(ctx, l)
+	l.ResultSourcedID = "updated"
+	s.SaveLink(ctx, l)
+	s.SaveLink(ctx, lti.Link{ActivityID: "quiz-2", ConsumerKey: "key", ResourceLinkID: "2", UserID: "u1"})
+
+	links, _ := s.Links(ctx, "quiz-1")
+	if len(links) != 1 || links[0].ResultSourcedID != "updated" {
+		t.Errorf("Link should be replaced, got %v", links)
+	}
+	if len(s.Saved()) != 3 {
+		t.Errorf("Every save should be recorded, got %v", s.Saved())
+	}
+}
+
+func TestAuthorizer(t *testing.T) {
+	a := &Authorizer{Err: lti.ErrUnauthorized}
+	h := lti.Protect(http.NotFoundHandler(), a)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if w.Code != http.StatusForbidden || a.Calls() != 1 {
+		t.Errorf("Request should be denied once, got %d after %d calls", w.Code, a.Calls())
+	}
+}
+
+func TestFieldEncrypter(t *testing.T) {
+	e := &FieldEncrypter{}
+	v := url.Values{"lis_person_name_full": {"Jane"}, "user_id": {"1"}}
+	enc, err := lti.EncryptParams(v, e, lti.DefaultRedactionPolicy)
+	if err != nil {
+		t.Fatalf("Error encrypting %s", err)
+	}
+	if enc.Get("lis_person_name_full") != "enc:lis_person_name_full:Jane" || enc.Get("user_id") != "1" {
+		t.Errorf("Unexpected encrypted params %v", enc)
+	}
+	dec, err := lti.DecryptParams(enc, e, lti.DefaultRedactionPolicy)
+	if err != nil || dec.Get("lis_person_name_full") != "Jane" {
+		t.Errorf("Params should be decrypted, got %v %v", dec, err)
+	}
+	if _, err := e.Decrypt("user_id", "plain"); err != ErrNotEncrypted {
+		t.Errorf("Expected ErrNotEncrypted, got %v", err)
+	}
+}
+
+func TestLaunchVerifier(t *testing.T) {
+	v := &LaunchVerifier{Params: url.Values{"resource_link_id": {"1"}}}
+	ts := lti.NewToolSet(v)
+	ts.Handle("/quiz", func(w http.ResponseWriter, r *http.Request, p *lti.Provider) {
+		w.Write([]byte(p.Get("resource_link_id")))
+	})
+	w := httptest.NewRecorder()
+	ts.ServeHTTP(w, httptest.NewRequest("POST", "/quiz", nil))
+	if w.Code != http.StatusOK || w.Body.String() != "1" || len(v.Requests()) != 1 {
+		t.Errorf("Launch should be accepted, got %d %s", w.Code, w.Body)
+	}
+
+	v.Err = lti.ErrInvalidSignature
+	w = httptest.NewRecorder()
+	ts.ServeHTTP(w, httptest.NewRequest("POST", "/quiz", nil))
+	if w.Code != http.StatusUnauthorized || len(v.Requests()) != 2 {
+		t.Errorf("Launch should be rejected, got %d", w.Code)
+	}
+}
+
+func TestRequestSigner(t *testing.T) {
+	s := &RequestSigner{}
+	r := httptest.NewRequest("POST", "/outcomes", nil)
+	if err := s.SignRequest(r); err != nil || r.Header.Get("Authorization") != "OAuth test" {
+		t.Errorf("Request should be signed, got %q %v", r.Header.Get("Authorization"), err)
+	}
+	s.Err = errors.New("no key")
+	if err := s.SignRequest(httptest.NewRequest("POST", "/outcomes", nil)); err != s.Err {
+		t.Errorf("Expected Err, got %v", err)
+	}
+	if len(s.Requests()) != 2 {
+		t.Errorf("Requests should be recorded, got %d", len(s.Requests()))
+	}
+}
+
+func TestLogger(t *testing.T) {
+	l := &Logger{}
+	p := lti.NewProvider("secret", "http://localhost/", lti.WithDebug(l))
+	p.Add("resource_link_id", "1")
+	p.Sign()
+	lines := l.Lines()
+	if len(lines) == 0 || !strings.Contains(lines[0], "base string") {
+		t.Errorf("Debug output should be logged, got %q", lines)
+	}
+}
+
+func TestSoakNonceStore(t *testing.T) {
+	n := 1000000
+	if testing.Short() {
+		n = 100000
+	}
+	ttl, interval := time.Minute, time.Millisecond
+	clock := NewClock(time.Now())
+	s := lti.NewMemoryNonceStore(ttl)
+	s.Clock = clock
+	st, err := SoakNonceStore(s, clock, n, interval)
+	if err != nil {
+		t.Fatalf("Soak failed %s", err)
+	}
+	// nonces are swept once per ttl, so at most two windows are kept
+	if limit := int(2*ttl/interval) + 1; st.MaxEntries > limit {
+		t.Errorf("Store should be bounded by %d entries, got %d", limit, st.MaxEntries)
+	}
+	if st.Final.Evicted == 0 || int64(st.Final.Entries)+st.Final.Evicted != int64(n) {
+		t.Errorf("Every nonce should be kept or evicted, got %+v", st.Final)
+	}
+
+	// stores without stats are soaked too
+	replaying := &NonceStore{}
+	if st, err := SoakNonceStore(replaying, clock, 10, interval); err != nil || st.MaxEntries != 0 {
+		t.Errorf("Unexpected soak of a store without stats %+v %v", st, err)
+	}
+	replaying.Replay = true
+	if _, err := SoakNonceStore(replaying, clock, 10, interval); err == nil {
+		t.Error("A fresh nonce reported as seen should fail the soak")
+	}
+}
+
+func TestClock(t *testing.T) {
+	start := time.Unix(1348093590, 0)
+	c := NewClock(start)
+	if !c.Now().Equal(start) {
+		t.Errorf("Clock should start at %s, got %s", start, c.Now())
+	}
+	if now := c.Advance(time.Minute); !now.Equal(start.Add(time.Minute)) || !c.Now().Equal(now) {
+		t.Errorf("Clock should advance, got %s", c.Now())
+	}
+	var _ oauth.Clock = c
+}
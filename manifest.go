@@ -0,0 +1,46 @@
+package lti
+
+import "github.com/jordic/lti/oauth"
+
+// Capabilities describes what this version of the package supports, to
+// be exposed by tools, so support teams know what a deployed tool can
+// do:
+//
+//  http.HandleFunc("/.well-known/lti", func(w http.ResponseWriter, r *http.Request) {
+//    json.NewEncoder(w).Encode(lti.Manifest())
+//  })
+type Capabilities struct {
+	// Version is the version of the package.
+	Version string `json:"version"`
+	// LTIVersions are the lti_version values accepted.
+	LTIVersions []string `json:"lti_versions"`
+	// MessageTypes are the lti_message_type values accepted.
+	MessageTypes []string `json:"message_types"`
+	// SignatureMethods are the OAuth signature methods that can
+	// be used to sign and verify messages.
+	SignatureMethods []string `json:"signature_methods"`
+	// Services are the LTI services clients provided.
+	Services []string `json:"services"`
+	// Features are the optional OAuth features supported.
+	Features []string `json:"features"`
+}
+
+// signatureMethods are the signature methods of the signers of the
+// oauth package.
+var signatureMethods = []string{oauth.HMACSHA1, oauth.HMACSHA256, oauth.RSASHA1, oauth.PLAINTEXT}
+
+// Manifest returns the capabilities of the package.
+func Manifest() *Capabilities {
+	return &Capabilities{
+		Version:          Version,
+		LTIVersions:      append([]string(nil), supportedVersions...),
+		MessageTypes:     []string{LaunchMessageType},
+		SignatureMethods: append([]string(nil), signatureMethods...),
+		Services:         []string{},
+		Features: []string{
+			"oauth_body_hash",
+			"authorization_header",
+			"nonce_replay_protection",
+		},
+	}
+}
@@ -0,0 +1,28 @@
+package lti
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestManifest(t *testing.T) {
+	m := Manifest()
+	if m.Version != Version || len(m.LTIVersions) == 0 || m.MessageTypes[0] != LaunchMessageType {
+		t.Errorf("Unexpected manifest %+v", m)
+	}
+	m.LTIVersions[0] = "changed"
+	if Manifest().LTIVersions[0] == "changed" {
+		t.Error("Manifest should not share the package state")
+	}
+
+	b, err := json.Marshal(Manifest())
+	if err != nil {
+		t.Fatalf("Error marshaling manifest %s", err)
+	}
+	for _, s := range []string{`"lti_versions":["LTI-1p0","LTI-1p2"]`, `"services":[]`, `"HMAC-SHA1"`} {
+		if !strings.Contains(string(b), s) {
+			t.Errorf("Manifest should contain %s, got %s", s, b)
+		}
+	}
+}
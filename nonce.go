@@ -0,0 +1,166 @@
+package lti
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/jordic/lti/oauth"
+)
+
+// DefaultClockSkew is how far the oauth_timestamp of a request can
+// be from the current time, when Provider.Nonces is set and
+// Provider.ClockSkew is not.
+const DefaultClockSkew = 5 * time.Minute
+
+// clockSkew returns the clock skew allowed by p.
+func (p *Provider) clockSkew() time.Duration {
+	if p.ClockSkew > 0 {
+		return p.ClockSkew
+	}
+	return DefaultClockSkew
+}
+
+// checkTimestamp parses ts and checks it is recent, when ClockSkew
+// or Nonces are set. Otherwise any timestamp is accepted, as the
+// providers did before the check was added.
+func (p *Provider) checkTimestamp(ts string) (time.Time, error) {
+	sec, err := strconv.ParseInt(ts, 10, 64)
+	if p.ClockSkew <= 0 && p.Nonces == nil {
+		return time.Unix(sec, 0), nil
+	}
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: invalid oauth_timestamp %q", ErrExpiredTimestamp, ts)
+	}
+	t := time.Unix(sec, 0)
+	if d, skew := p.now().Sub(t), p.clockSkew(); d > skew || d < -skew {
+		return t, fmt.Errorf("%w: oauth_timestamp %s is %s away from now",
+			ErrExpiredTimestamp, ts, d.Round(time.Second))
+	}
+	return t, nil
+}
+
+// checkNonce records the nonce in p.Nonces, if set, failing if it
+// was already seen.
+func (p *Provider) checkNonce(consumerKey, nonce string, ts time.Time) error {
+	if p.Nonces == nil {
+		return nil
+	}
+	if nonce == "" {
+		return fmt.Errorf("%w: missing oauth_nonce", ErrReplayedNonce)
+	}
+	seen, err := p.Nonces.Seen(consumerKey, nonce, ts)
+	if err != nil {
+		return err
+	}
+	if seen {
+		return fmt.Errorf("%w %s", ErrReplayedNonce, nonce)
+	}
+	return nil
+}
+
+// NonceStore keeps the nonces of the requests verified, so replayed
+// requests can be detected. Nonces only need to be kept while their
+// timestamp is accepted.
+type NonceStore interface {
+	// Seen records the nonce sent by consumerKey at ts, and
+	// reports if it was already recorded.
+	Seen(consumerKey, nonce string, ts time.Time) (bool, error)
+}
+
+// MemoryNonceStore is a NonceStore kept in memory. Nonces are
+// evicted once their timestamp is older than the TTL.
+type MemoryNonceStore struct {
+	// Clock, when set, gives the time evictions are based on,
+	// time.Now if nil.
+	Clock oauth.Clock
+
+	ttl time.Duration
+
+	mu        sync.Mutex
+	nonces    map[string]time.Time
+	lastSweep time.Time
+	evicted   int64
+}
+
+// NonceStatsReporter is implemented by the NonceStores that report
+// their state, like MemoryNonceStore.
+type NonceStatsReporter interface {
+	Stats() NonceStats
+}
+
+// NonceStats holds the state of a NonceStore, for monitoring.
+type NonceStats struct {
+	// Entries is the number of nonces kept.
+	Entries int
+	// Evicted is the number of nonces evicted since the store was
+	// created.
+	Evicted int64
+}
+
+// NewMemoryNonceStore returns a MemoryNonceStore keeping the nonces
+// during ttl, that should be at least the accepted timestamp window.
+func NewMemoryNonceStore(ttl time.Duration) *MemoryNonceStore {
+	return &MemoryNonceStore{
+		ttl:    ttl,
+		nonces: map[string]time.Time{},
+	}
+}
+
+// Seen records the nonce sent by consumerKey at ts, and reports
+// if it was already recorded.
+func (s *MemoryNonceStore) Seen(consumerKey, nonce string, ts time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// the sweeps start on the first nonce, once the Clock is set
+	if now := s.now(); s.lastSweep.IsZero() {
+		s.lastSweep = now
+	} else if now.Sub(s.lastSweep) > s.ttl {
+		s.purge(now.Add(-s.ttl))
+		s.lastSweep = now
+	}
+
+	k := consumerKey + "\x00" + nonce
+	if _, ok := s.nonces[k]; ok {
+		return true, nil
+	}
+	s.nonces[k] = ts
+	return false, nil
+}
+
+// Stats returns the number of nonces kept, and evicted so far.
+func (s *MemoryNonceStore) Stats() NonceStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return NonceStats{Entries: len(s.nonces), Evicted: s.evicted}
+}
+
+// now returns the current time, from Clock when set.
+func (s *MemoryNonceStore) now() time.Time {
+	if s.Clock != nil {
+		return s.Clock.Now()
+	}
+	return time.Now()
+}
+
+// Purge removes the nonces with a timestamp before the time given.
+func (s *MemoryNonceStore) Purge(ctx context.Context, before time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.purge(before), nil
+}
+
+func (s *MemoryNonceStore) purge(before time.Time) int {
+	n := 0
+	for k, t := range s.nonces {
+		if t.Before(before) {
+			delete(s.nonces, k)
+			n++
+		}
+	}
+	s.evicted += int64(n)
+	return n
+}
@@ -0,0 +1,20 @@
+package lti
+
+import (
+	"net/url"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// normalizeValues returns a copy of v with its names and values
+// normalized to NFC.
+func normalizeValues(v url.Values) url.Values {
+	n := make(url.Values, len(v))
+	for k, vs := range v {
+		k = norm.NFC.String(k)
+		for _, s := range vs {
+			n[k] = append(n[k], norm.NFC.String(s))
+		}
+	}
+	return n
+}
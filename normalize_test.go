@@ -0,0 +1,41 @@
+package lti
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestNormalizeUnicode(t *testing.T) {
+	const nfc, nfd = "Jos\u00e9", "Jose\u0301"
+
+	c := NewProvider("asdf", "http://urltest.com/", WithConsumerKey("12345"))
+	c.Add("resource_link_id", "1").Add("lis_person_name_full", nfc)
+	c.Sign()
+	form := c.Params()
+	form.Set("lis_person_name_full", nfd)
+
+	p := NewProvider("asdf", "http://urltest.com/", WithConsumerKey("12345"))
+	if _, err := p.IsValid(&http.Request{Method: "POST", Form: form}); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("NFD delivery of a NFC signature should fail without normalization, got %v", err)
+	}
+	p.NormalizeUnicode = true
+	if ok, err := p.IsValid(&http.Request{Method: "POST", Form: form}); !ok {
+		t.Errorf("NFD delivery should be valid with normalization, got %s", err)
+	}
+	if p.Get("lis_person_name_full") != nfd {
+		t.Error("Params should be kept as received")
+	}
+
+	// a consumer normalizing signs NFD values as NFC.
+	c = NewProvider("asdf", "http://urltest.com/", WithConsumerKey("12345"),
+		WithUnicodeNormalization())
+	c.Add("resource_link_id", "1").Add("lis_person_name_full", nfd)
+	c.Sign()
+	form = c.Params()
+	form.Set("lis_person_name_full", nfc)
+	p.NormalizeUnicode = false
+	if ok, err := p.IsValid(&http.Request{Method: "POST", Form: form}); !ok {
+		t.Errorf("Normalized signature should be valid, got %s", err)
+	}
+}
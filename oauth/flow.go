@@ -0,0 +1,135 @@
+package oauth
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Credentials are the temporary or token credentials returned by a
+// server in the three-legged flow.
+type Credentials struct {
+	Token  string
+	Secret string
+	// Extra holds the rest of the params of the response, like the
+	// user_id some servers add.
+	Extra url.Values
+}
+
+// Consumer drives the three-legged flow of RFC 5849 section 2, to
+// get the token credentials of a user of a server:
+//
+//  c := &oauth.Consumer{Key: key, Secret: secret, ...}
+//  temp, err := c.RequestTemporaryCredentials(ctx, "https://tool.example.com/callback")
+//  http.Redirect(w, r, c.AuthorizeURL(temp), http.StatusFound)
+//
+//  // on the callback
+//  token, err := c.RequestTokenCredentials(ctx, temp, r.FormValue("oauth_verifier"))
+//  o := c.Params(token)
+type Consumer struct {
+	Key    string
+	Secret string
+	// The endpoints of the server.
+	TemporaryCredentialsURL string
+	AuthorizationURL        string
+	TokenURL                string
+	// NewSigner returns the signer of the requests with the secrets
+	// given, GetHMACSigner if nil.
+	NewSigner func(clientSecret, tokenSecret string) OauthSigner
+	// Client sends the requests, http.DefaultClient if nil.
+	Client *http.Client
+}
+
+// maxCredentialsResponse limits the responses read by Consumer.
+const maxCredentialsResponse = 1 << 16
+
+// Params returns the OAuthParameters signing requests with the
+// token credentials, or only the consumer ones if token is nil.
+func (c *Consumer) Params(token *Credentials) *OAuthParameters {
+	newSigner := c.NewSigner
+	if newSigner == nil {
+		newSigner = func(clientSecret, tokenSecret string) OauthSigner {
+			return GetHMACSigner(clientSecret, tokenSecret)
+		}
+	}
+	key, secret := c.Key, c.Secret
+	o := &OAuthParameters{
+		ConsumerKey:    &key,
+		ConsumerSecret: &secret,
+		Client:         c.Client,
+	}
+	tokenSecret := ""
+	if token != nil {
+		t, s := token.Token, token.Secret
+		o.Token, o.TokenSecret, tokenSecret = &t, &s, s
+	}
+	o.Signer = newSigner(c.Secret, tokenSecret)
+	return o
+}
+
+// RequestTemporaryCredentials asks the server for temporary
+// credentials, to be authorized by the user. callback is the URL the
+// user is sent back to, "oob" when there is none.
+func (c *Consumer) RequestTemporaryCredentials(ctx context.Context, callback string) (*Credentials, error) {
+	o := c.Params(nil)
+	o.Callback = &callback
+	creds, err := c.request(ctx, o, c.TemporaryCredentialsURL)
+	if err != nil {
+		return nil, err
+	}
+	if creds.Extra.Get("oauth_callback_confirmed") != "true" {
+		return nil, ErrF("oauth_callback_confirmed missing from the temporary credentials")
+	}
+	return creds, nil
+}
+
+// AuthorizeURL returns the URL of the server to send the user to,
+// to authorize the temporary credentials.
+func (c *Consumer) AuthorizeURL(temp *Credentials) string {
+	sep := "?"
+	if strings.Contains(c.AuthorizationURL, "?") {
+		sep = "&"
+	}
+	return c.AuthorizationURL + sep + "oauth_token=" + Encode(temp.Token)
+}
+
+// RequestTokenCredentials exchanges the temporary credentials,
+// authorized by the user, for token credentials. verifier is the
+// oauth_verifier received by the callback.
+func (c *Consumer) RequestTokenCredentials(ctx context.Context, temp *Credentials, verifier string) (*Credentials, error) {
+	o := c.Params(temp)
+	o.Verifier = &verifier
+	return c.request(ctx, o, c.TokenURL)
+}
+
+// request POSTs a signed request to u, parsing the credentials of
+// the response.
+func (c *Consumer) request(ctx context.Context, o *OAuthParameters, u string) (*Credentials, error) {
+	resp, err := o.Do(ctx, "POST", u, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxCredentialsResponse))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, ErrF("%s answered %s: %s", u, resp.Status, body)
+	}
+	v, err := url.ParseQuery(string(body))
+	if err != nil {
+		return nil, err
+	}
+	creds := &Credentials{Token: v.Get("oauth_token"), Secret: v.Get("oauth_token_secret")}
+	if creds.Token == "" {
+		return nil, ErrF("oauth_token missing from the response of %s", u)
+	}
+	v.Del("oauth_token")
+	v.Del("oauth_token_secret")
+	creds.Extra = v
+	return creds, nil
+}
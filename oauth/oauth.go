@@ -22,19 +22,26 @@
 package oauth
 
 import (
+	"bytes"
+	"context"
 	"crypto"
 	"crypto/hmac"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
 	"encoding/base64"
-	"encoding/binary"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
+	"mime"
 	"net/http"
 	"net/url"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -45,6 +52,54 @@ type KV struct {
 	Val string
 }
 
+// KVsFromValues returns every value of v as a KV, sorted by key.
+func KVsFromValues(v url.Values) []KV {
+	keys := make([]string, 0, len(v))
+	for k := range v {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var kvs []KV
+	for _, k := range keys {
+		for _, val := range v[k] {
+			kvs = append(kvs, KV{k, val})
+		}
+	}
+	return kvs
+}
+
+// ToValues returns kvs as url.Values, keeping the values of
+// repeated keys in order.
+func ToValues(kvs []KV) url.Values {
+	v := url.Values{}
+	for _, kv := range kvs {
+		v.Add(kv.Key, kv.Val)
+	}
+	return v
+}
+
+// KVsFromMap returns m as KVs, sorted by key.
+func KVsFromMap(m map[string]string) []KV {
+	kvs := make([]KV, 0, len(m))
+	for k, v := range m {
+		kvs = append(kvs, KV{k, v})
+	}
+	OauthKvSort(kvs)
+	return kvs
+}
+
+// ToMap returns kvs as a map, holding the first value of repeated
+// keys, as url.Values.Get does.
+func ToMap(kvs []KV) map[string]string {
+	m := make(map[string]string, len(kvs))
+	for _, kv := range kvs {
+		if _, ok := m[kv.Key]; !ok {
+			m[kv.Key] = kv.Val
+		}
+	}
+	return m
+}
+
 func ErrF(format string, parameters ...interface{}) error {
 	return errors.New(fmt.Sprintf(format, parameters...))
 }
@@ -84,24 +139,109 @@ func MergeRequestParameters(query, oauth, form []KV) []KV {
 	return allParameters
 }
 
-// GetBaseString returns the 'Signature Base String', which is to be encoded as the signature
+// GetBaseString returns the 'Signature Base String', which is to be encoded as the signature.
+// allParameters is not modified, oauth_signature is left out of it.
 func GetBaseString(method, requestUrl string, allParameters []KV) (string, error) {
+	requestUrl, err := NormalizeURL(requestUrl)
+	if err != nil {
+		return "", err
+	}
 
-	for i, kv := range allParameters {
-		allParameters[i].Val = url.QueryEscape(kv.Val)
-		allParameters[i].Key = url.QueryEscape(kv.Key)
+	encoded := make([]KV, 0, len(allParameters))
+	size := len(method) + 3*len(requestUrl) + 2
+	for _, kv := range allParameters {
+		if kv.Key == "oauth_signature" {
+			continue
+		}
+		kv = KV{Encode(kv.Key), Encode(kv.Val)}
+		encoded = append(encoded, kv)
+		// the encoded params are encoded again, so up to 3 times
+		size += 3*(len(kv.Key)+len(kv.Val)) + 6
 	}
+	OauthKvSort(encoded)
 
-	OauthKvSort(allParameters)
+	var b strings.Builder
+	b.Grow(size)
+	writeEncoded(&b, strings.ToUpper(method))
+	b.WriteByte('&')
+	writeEncoded(&b, requestUrl)
+	b.WriteByte('&')
+	for i, kv := range encoded {
+		if i > 0 {
+			b.WriteString("%26")
+		}
+		writeEncoded(&b, kv.Key)
+		b.WriteString("%3D")
+		writeEncoded(&b, kv.Val)
+	}
+	return b.String(), nil
+}
 
-	strs := make([]string, len(allParameters), len(allParameters))
-	for i, kv := range allParameters {
-		strs[i] = kv.Key + "=" + kv.Val
+// Encode encodes s as RFC 5849 section 3.6 says: every byte
+// but the unreserved chars (ALPHA, DIGIT, '-', '.', '_', '~') is
+// encoded as %XX with uppercase hex digits, so spaces are %20 and
+// not '+' as url.QueryEscape does. Use it for anything signed or
+// sent in OAuth params.
+func Encode(s string) string {
+	n := 0
+	for i := 0; i < len(s); i++ {
+		if !isUnreserved(s[i]) {
+			n++
+		}
+	}
+	if n == 0 {
+		return s
 	}
+	var b strings.Builder
+	b.Grow(len(s) + 2*n)
+	writeEncoded(&b, s)
+	return b.String()
+}
 
-	urlPart := url.QueryEscape(strings.ToUpper(method)) + "&" + url.QueryEscape(requestUrl)
+// writeEncoded writes s to b encoded as Encode does.
+func writeEncoded(b *strings.Builder, s string) {
+	const hex = "0123456789ABCDEF"
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if isUnreserved(c) {
+			b.WriteByte(c)
+			continue
+		}
+		b.WriteByte('%')
+		b.WriteByte(hex[c>>4])
+		b.WriteByte(hex[c&15])
+	}
+}
 
-	return urlPart + "&" + url.QueryEscape(strings.Join(strs, "&")), nil
+func isUnreserved(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' ||
+		c == '-' || c == '.' || c == '_' || c == '~'
+}
+
+// NormalizeURL returns the base string URI of a request URL, as
+// defined in RFC 5849 section 3.4.1.2: scheme and host lowercased,
+// default ports (80 for http, 443 for https) removed, and query and
+// fragment dropped.
+func NormalizeURL(requestUrl string) (string, error) {
+	u, err := url.Parse(requestUrl)
+	if err != nil {
+		return "", err
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	host, port := strings.ToLower(u.Hostname()), u.Port()
+	if strings.Contains(host, ":") {
+		host = "[" + host + "]"
+	}
+	if port != "" && !(u.Scheme == "http" && port == "80") && !(u.Scheme == "https" && port == "443") {
+		host += ":" + port
+	}
+	u.Host = host
+	u.RawQuery, u.Fragment, u.RawFragment = "", "", ""
+	u.ForceQuery = false
+	if u.Path == "" {
+		u.Path = "/"
+	}
+	return u.String(), nil
 }
 
 // OauthSigner should have implementations for all signature methods for oAuth
@@ -110,9 +250,41 @@ type OauthSigner interface {
 	GetMethod() string
 }
 
+// OauthVerifier should have implementations for all signature methods
+// for oAuth, to verify the signatures received
+type OauthVerifier interface {
+	Verify(baseString, signature string) error
+	GetMethod() string
+}
+
+// ErrSignatureMismatch is returned by the verifiers of symmetric
+// methods for signatures that don't match.
+var ErrSignatureMismatch = errors.New("signature mismatch")
+
+// verifyBySigning verifies signature signing baseString with s.
+func verifyBySigning(s OauthSigner, baseString, signature string) error {
+	sig, err := s.GetSignature(baseString)
+	if err != nil {
+		return err
+	}
+	if !hmac.Equal([]byte(sig), []byte(signature)) {
+		return ErrSignatureMismatch
+	}
+	return nil
+}
+
+// Signature methods of the signers
+const (
+	HMACSHA1   = "HMAC-SHA1"
+	HMACSHA256 = "HMAC-SHA256"
+	RSASHA1    = "RSA-SHA1"
+	RSASHA256  = "RSA-SHA256"
+	PLAINTEXT  = "PLAINTEXT"
+)
+
 // GetHMACSigner generates the HMAC-SHA1 signing algorythm
 func GetHMACSigner(clientSecret, tokenSecret string) *HMACSigner {
-	key := url.QueryEscape(clientSecret) + "&" + url.QueryEscape(tokenSecret)
+	key := Encode(clientSecret) + "&" + Encode(tokenSecret)
 
 	hms := HMACSigner{
 		clientSecret: clientSecret,
@@ -134,7 +306,48 @@ func (s *HMACSigner) GetSignature(baseString string) (string, error) {
 	mac.Write([]byte(baseString))
 	return base64.StdEncoding.EncodeToString(mac.Sum(nil)), nil
 }
-func (s *HMACSigner) GetMethod() string { return "HMAC-SHA1" }
+func (s *HMACSigner) GetMethod() string { return HMACSHA1 }
+func (s *HMACSigner) Verify(baseString, signature string) error {
+	return verifyBySigning(s, baseString, signature)
+}
+
+// GetHMAC256Signer generates the HMAC-SHA256 signing algorythm
+func GetHMAC256Signer(clientSecret, tokenSecret string) *HMAC256Signer {
+	return &HMAC256Signer{*GetHMACSigner(clientSecret, tokenSecret)}
+}
+
+type HMAC256Signer struct {
+	HMACSigner
+}
+
+func (s *HMAC256Signer) GetSignature(baseString string) (string, error) {
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write([]byte(baseString))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil)), nil
+}
+func (s *HMAC256Signer) GetMethod() string { return HMACSHA256 }
+func (s *HMAC256Signer) Verify(baseString, signature string) error {
+	return verifyBySigning(s, baseString, signature)
+}
+
+// GetPlaintextSigner generates the PLAINTEXT signing method, where
+// the signature is the secrets themselves, so it must only be used
+// over TLS.
+func GetPlaintextSigner(clientSecret, tokenSecret string) *PlaintextSigner {
+	return &PlaintextSigner{Encode(clientSecret) + "&" + Encode(tokenSecret)}
+}
+
+type PlaintextSigner struct {
+	signature string
+}
+
+func (s *PlaintextSigner) GetSignature(baseString string) (string, error) {
+	return s.signature, nil
+}
+func (s *PlaintextSigner) GetMethod() string { return PLAINTEXT }
+func (s *PlaintextSigner) Verify(baseString, signature string) error {
+	return verifyBySigning(s, baseString, signature)
+}
 
 // GetRSASigner generates the RSA-SHA1 signing algorythm
 func GetRSASigner(privateKey *rsa.PrivateKey) *RSASigner {
@@ -161,7 +374,48 @@ func (s *RSASigner) GetSignature(baseString string) (string, error) {
 	return base64.StdEncoding.EncodeToString(b), nil
 }
 
-func (s *RSASigner) GetMethod() string { return "RSA-SHA1" }
+func (s *RSASigner) GetMethod() string { return RSASHA1 }
+
+// GetRSAVerifier generates the RSA-SHA1 verifying algorythm
+func GetRSAVerifier(publicKey *rsa.PublicKey) *RSAVerifier {
+	return &RSAVerifier{publicKey: publicKey, hash: crypto.SHA1, method: RSASHA1}
+}
+
+// GetRSA256Verifier generates the RSA-SHA256 verifying algorythm
+func GetRSA256Verifier(publicKey *rsa.PublicKey) *RSAVerifier {
+	return &RSAVerifier{publicKey: publicKey, hash: crypto.SHA256, method: RSASHA256}
+}
+
+// GetRSAVerifierFromCertificate generates the RSA-SHA1 verifying
+// algorythm with the public key of cert, that must be an RSA one.
+func GetRSAVerifierFromCertificate(cert *x509.Certificate) (*RSAVerifier, error) {
+	pk, ok := cert.PublicKey.(*rsa.PublicKey)
+	if !ok {
+		return nil, ErrF("certificate without an RSA public key")
+	}
+	return GetRSAVerifier(pk), nil
+}
+
+// RSAVerifier verifies the signatures made with the private key of
+// its public key.
+type RSAVerifier struct {
+	publicKey *rsa.PublicKey
+	hash      crypto.Hash
+	method    string
+}
+
+// Verify checks that signature is the signature of baseString.
+func (v *RSAVerifier) Verify(baseString, signature string) error {
+	sig, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return err
+	}
+	h := v.hash.New()
+	h.Write([]byte(baseString))
+	return rsa.VerifyPKCS1v15(v.publicKey, v.hash, h.Sum(nil), sig)
+}
+
+func (v *RSAVerifier) GetMethod() string { return v.method }
 
 type OAuthParameters struct {
 	Signer         OauthSigner
@@ -173,34 +427,270 @@ type OAuthParameters struct {
 	Method         *string
 	Nonce          *string
 	Timestamp      *string
+	// BodyHash, when set, is sent and signed as oauth_body_hash,
+	// see SetBody.
+	BodyHash *string
+	// Realm, when set, is sent as the first param of the
+	// Authorization header, it is not signed.
+	Realm *string
+	// Client sends the requests of DoOauthRequest, with its
+	// timeouts, proxies and transport, http.DefaultClient if nil.
+	Client *http.Client
+	// Retry, when set, retries the requests failing with a
+	// connection error or a 5xx.
+	Retry *RetryPolicy
+	// NonceSource and Clock, when set, give the nonce and the
+	// timestamp of the requests, RandomNonce and time.Now if nil.
+	NonceSource NonceSource
+	Clock       Clock
+	// Callback is sent as oauth_callback when requesting temporary
+	// credentials, "oob" when there is no callback URL. Token is
+	// not required then.
+	Callback *string
+	// Verifier is sent as oauth_verifier when requesting the token
+	// credentials, as received by the callback.
+	Verifier *string
+	// Timeout limits each attempt of a request, response body
+	// included, DefaultTimeout if zero, none if negative.
+	Timeout time.Duration
+	// MaxResponseSize limits the bodies read by DoOauthRequest,
+	// DefaultMaxResponseSize if zero.
+	MaxResponseSize int64
+	// Strict makes Check reject a Version other than 1.0, and a
+	// Method other than the one of the Signer.
+	Strict bool
+}
+
+// Defaults of the limits of OAuthParameters.
+const (
+	DefaultTimeout         = 30 * time.Second
+	DefaultMaxResponseSize = 10 << 20
+)
+
+// ErrResponseTooLarge is returned by DoOauthRequest for responses
+// larger than MaxResponseSize.
+var ErrResponseTooLarge = errors.New("response too large")
+
+// maxDrain limits what is read of a discarded response, so its
+// connection can be reused.
+const maxDrain = 64 << 10
+
+// RetryPolicy tells how to retry the requests of OAuthParameters.
+// Each attempt is signed with a fresh nonce and timestamp, waiting
+// Backoff before the first retry, doubled on each one up to
+// MaxBackoff:
+//
+//  o.Retry = &oauth.RetryPolicy{MaxAttempts: 3, Backoff: time.Second}
+type RetryPolicy struct {
+	// MaxAttempts is the number of attempts, the first included.
+	MaxAttempts int
+	Backoff     time.Duration
+	MaxBackoff  time.Duration
+}
+
+// wait returns the wait before the retry n, from 1.
+func (p *RetryPolicy) wait(n int) time.Duration {
+	d := p.Backoff
+	for i := 1; i < n; i++ {
+		if d *= 2; p.MaxBackoff > 0 && d > p.MaxBackoff {
+			return p.MaxBackoff
+		}
+	}
+	if p.MaxBackoff > 0 && d > p.MaxBackoff {
+		return p.MaxBackoff
+	}
+	return d
+}
+
+// BodyHash returns the oauth_body_hash of body, hashed with hash,
+// which must be linked into the binary:
+//
+//  oauth.BodyHash([]byte(xml), crypto.SHA1)
+func BodyHash(body []byte, hash crypto.Hash) string {
+	h := hash.New()
+	h.Write(body)
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+// SetBody sets the BodyHash of the body of a request with a body
+// other than a form, like the XML of the LTI outcomes, hashed as the
+// signature method says: SHA-256 for the SHA-256 methods, and SHA-1
+// for the rest.
+func (o *OAuthParameters) SetBody(body []byte) {
+	h := BodyHash(body, o.bodyHashAlgorithm())
+	o.BodyHash = &h
 }
 
+// SetBodyReader is SetBody reading the body from r, without holding
+// it in memory, for large bodies like the files of multipart
+// submissions.
+func (o *OAuthParameters) SetBodyReader(r io.Reader) error {
+	h, err := BodyHashReader(r, o.bodyHashAlgorithm())
+	if err != nil {
+		return err
+	}
+	o.BodyHash = &h
+	return nil
+}
+
+// bodyHashAlgorithm returns the hash of the oauth_body_hash for the
+// signature method.
+func (o *OAuthParameters) bodyHashAlgorithm() crypto.Hash {
+	method := o.Signer.GetMethod()
+	if o.Method != nil {
+		method = *o.Method
+	}
+	return BodyHashAlgorithm(method)
+}
+
+// BodyHashAlgorithm returns the hash of the oauth_body_hash of the
+// requests signed with method: SHA-256 for the *-SHA256 methods, and
+// SHA-1 for the others.
+func BodyHashAlgorithm(method string) crypto.Hash {
+	if strings.HasSuffix(method, "-SHA256") {
+		return crypto.SHA256
+	}
+	return crypto.SHA1
+}
+
+// BodyHashReader is BodyHash reading the body from r as a stream.
+func BodyHashReader(r io.Reader, hash crypto.Hash) (string, error) {
+	h := hash.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(h.Sum(nil)), nil
+}
+
+// NonceSource returns the oauth_nonce of the requests.
+type NonceSource interface {
+	Nonce() (string, error)
+}
+
+// NonceFunc is a function used as NonceSource.
+type NonceFunc func() (string, error)
+
+// Nonce returns f().
+func (f NonceFunc) Nonce() (string, error) { return f() }
+
+// RandomNonce is the default NonceSource, returning 16 random bytes
+// hex encoded.
+var RandomNonce NonceSource = NonceFunc(func() (string, error) {
+	b := make([]byte, 16)
+	if _, err := io.ReadFull(rand.Reader, b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+})
+
+// Clock returns the time of the oauth_timestamp of the requests.
+type Clock interface {
+	Now() time.Time
+}
+
+// ClockFunc is a function used as Clock, like time.Now.
+type ClockFunc func() time.Time
+
+// Now returns f().
+func (f ClockFunc) Now() time.Time { return f() }
+
+// Build sets a new Nonce and Timestamp, from NonceSource and Clock
+// when set. The Nonce is left unset if NonceSource fails.
 func (o *OAuthParameters) Build() {
-	var nonceInt uint64
-	binary.Read(rand.Reader, binary.LittleEndian, &nonceInt)
-	nonceString := fmt.Sprintf("%d", nonceInt)
-	o.Nonce = &nonceString
-	timestampString := fmt.Sprintf("%d", time.Now().Unix())
+	o.build()
+}
+
+func (o *OAuthParameters) build() error {
+	ns := o.NonceSource
+	if ns == nil {
+		ns = RandomNonce
+	}
+	nonce, err := ns.Nonce()
+	if err != nil {
+		return err
+	}
+	o.Nonce = &nonce
+	now := time.Now
+	if o.Clock != nil {
+		now = o.Clock.Now
+	}
+	timestampString := strconv.FormatInt(now().Unix(), 10)
 	o.Timestamp = &timestampString
+	return nil
+}
+
+// Errors of the fields missing, found by Check.
+var (
+	ErrMissingConsumerKey = errors.New("Consumer Key not set")
+	ErrMissingToken       = errors.New("Token not set")
+	ErrMissingSigner      = errors.New("Signer not set")
+)
+
+// CheckError holds every problem found by Check, and the defaults it
+// applied, so a misconfiguration can be fixed in one pass. errors.Is
+// matches any of its Errors.
+type CheckError struct {
+	Errors []error
+	// Defaults holds the params set by default, like
+	// "oauth_version=1.0".
+	Defaults []string
 }
 
+func (e *CheckError) Error() string {
+	msgs := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		msgs[i] = err.Error()
+	}
+	s := strings.Join(msgs, "; ")
+	if len(e.Defaults) > 0 {
+		s += " (defaults applied: " + strings.Join(e.Defaults, ", ") + ")"
+	}
+	return s
+}
+
+// Unwrap returns Errors.
+func (e *CheckError) Unwrap() []error {
+	return e.Errors
+}
+
+// Check sets the defaults of the params not set, and checks the
+// required ones are, returning a *CheckError with every problem
+// found. Version and Method are only checked when Strict is set.
 func (o *OAuthParameters) Check() error {
+	e := &CheckError{}
 	if o.ConsumerKey == nil {
-		return ErrF("Consumer Key not set")
+		e.Errors = append(e.Errors, ErrMissingConsumerKey)
+	}
+	if o.Token == nil && o.Callback == nil {
+		e.Errors = append(e.Errors, ErrMissingToken)
 	}
-	if o.Token == nil {
-		return ErrF("Token not set")
+	if o.Signer == nil {
+		e.Errors = append(e.Errors, ErrMissingSigner)
 	}
 	if o.Version == nil {
 		v := "1.0"
 		o.Version = &v
+		e.Defaults = append(e.Defaults, "oauth_version="+v)
+	} else if o.Strict && *o.Version != "1.0" {
+		e.Errors = append(e.Errors, ErrF("Version %q, should be 1.0", *o.Version))
 	}
-	if o.Method == nil {
+	if o.Method == nil && o.Signer != nil {
 		method := o.Signer.GetMethod()
 		o.Method = &method
+		e.Defaults = append(e.Defaults, "oauth_signature_method="+method)
+	} else if o.Strict && o.Method != nil && o.Signer != nil && *o.Method != o.Signer.GetMethod() {
+		e.Errors = append(e.Errors, ErrF("Method %s doesn't match the signer method %s",
+			*o.Method, o.Signer.GetMethod()))
 	}
 	if o.Nonce == nil || o.Timestamp == nil {
-		o.Build()
+		if err := o.build(); err != nil {
+			e.Errors = append(e.Errors, err)
+		} else {
+			e.Defaults = append(e.Defaults, "oauth_nonce="+*o.Nonce, "oauth_timestamp="+*o.Timestamp)
+		}
+	}
+	if len(e.Errors) > 0 {
+		return e
 	}
 	return nil
 }
@@ -216,10 +706,21 @@ func (o *OAuthParameters) GetOauthParameters() ([]KV, error) {
 		KV{"oauth_consumer_key", *o.ConsumerKey},
 		KV{"oauth_nonce", *o.Nonce},
 		KV{"oauth_timestamp", *o.Timestamp},
-		KV{"oauth_token", *o.Token},
 		KV{"oauth_signature_method", *o.Method},
 		KV{"oauth_version", *o.Version},
 	}
+	if o.Token != nil {
+		oauthKeys = append(oauthKeys, KV{"oauth_token", *o.Token})
+	}
+	if o.Callback != nil {
+		oauthKeys = append(oauthKeys, KV{"oauth_callback", *o.Callback})
+	}
+	if o.Verifier != nil {
+		oauthKeys = append(oauthKeys, KV{"oauth_verifier", *o.Verifier})
+	}
+	if o.BodyHash != nil {
+		oauthKeys = append(oauthKeys, KV{"oauth_body_hash", *o.BodyHash})
+	}
 	return oauthKeys, nil
 }
 
@@ -255,46 +756,452 @@ func (o *OAuthParameters) GetOAuthHeader(verb, requestUrl string, queryString []
 		return "", err
 	}
 	oauthParameters = append(oauthParameters, KV{"oauth_signature", sig})
+	// sorted, so the same request always renders the same header
+	OauthKvSort(oauthParameters)
 
-	oauthStrings := make([]string, len(oauthParameters), len(oauthParameters))
-	for i, kv := range oauthParameters {
-		oauthStrings[i] = fmt.Sprintf(`%s="%s"`, url.QueryEscape(kv.Key), url.QueryEscape(kv.Val))
+	var realm string
+	if o.Realm != nil {
+		realm = *o.Realm
+	}
+	return FormatAuthorizationHeader(realm, oauthParameters), nil
+}
+
+// FormatAuthorizationHeader returns the OAuth Authorization header
+// with params, in order, encoded once with Encode, as RFC 5849
+// section 3.5.1 says. The realm, if any, goes first as a quoted
+// string. It is the inverse of ParseAuthorizationHeader:
+//
+//  OAuth realm="Example", oauth_consumer_key="key", oauth_nonce="abc"
+func FormatAuthorizationHeader(realm string, params []KV) string {
+	var b strings.Builder
+	b.WriteString("OAuth ")
+	if realm != "" {
+		b.WriteString(`realm="`)
+		b.WriteString(strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(realm))
+		b.WriteByte('"')
+	}
+	for i, kv := range params {
+		if i > 0 || realm != "" {
+			b.WriteString(", ")
+		}
+		writeEncoded(&b, kv.Key)
+		b.WriteString(`="`)
+		writeEncoded(&b, kv.Val)
+		b.WriteByte('"')
+	}
+	return b.String()
+}
+
+// ParseAuthorizationHeader parses an OAuth Authorization header, as
+// sent with the signed service calls (outcomes, memberships),
+// returning its params percent-decoded, in order, and the realm,
+// which is not signed:
+//
+//  kvs, realm, err := oauth.ParseAuthorizationHeader(r.Header.Get("Authorization"))
+//
+// Values must be quoted, the quotes may hold backslash escapes.
+func ParseAuthorizationHeader(header string) ([]KV, string, error) {
+	const scheme = "OAuth"
+	h := strings.TrimSpace(header)
+	if len(h) < len(scheme) || !strings.EqualFold(h[:len(scheme)], scheme) ||
+		len(h) > len(scheme) && h[len(scheme)] != ' ' && h[len(scheme)] != '\t' {
+		return nil, "", ErrF("not an OAuth authorization header")
+	}
+	var kvs []KV
+	var realm string
+	s := h[len(scheme):]
+	for {
+		s = strings.TrimLeft(s, " \t,")
+		if s == "" {
+			return kvs, realm, nil
+		}
+		i := strings.IndexByte(s, '=')
+		if i <= 0 {
+			return nil, "", ErrF("malformed OAuth authorization header")
+		}
+		key := strings.TrimSpace(s[:i])
+		val, rest, err := unquote(strings.TrimLeft(s[i+1:], " \t"))
+		if err != nil {
+			return nil, "", err
+		}
+		s = strings.TrimLeft(rest, " \t")
+		if s != "" && s[0] != ',' {
+			return nil, "", ErrF("malformed OAuth authorization header, missing comma after %s", key)
+		}
+		if key == "realm" {
+			realm = val
+			continue
+		}
+		if key, err = url.PathUnescape(key); err != nil {
+			return nil, "", err
+		}
+		if val, err = url.PathUnescape(val); err != nil {
+			return nil, "", err
+		}
+		kvs = append(kvs, KV{key, val})
 	}
+}
 
-	return "OAuth " + strings.Join(oauthStrings, ", "), nil
+// unquote reads the quoted string s starts with, returning its value
+// and what follows it.
+func unquote(s string) (string, string, error) {
+	if s == "" || s[0] != '"' {
+		return "", "", ErrF("malformed OAuth authorization header, unquoted value")
+	}
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '"':
+			return b.String(), s[i+1:], nil
+		case c == '\\' && i+1 < len(s):
+			i++
+			b.WriteByte(s[i])
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", "", ErrF("malformed OAuth authorization header, unterminated value")
 }
 
 func (o *OAuthParameters) DoOauthRequest(verb string, requestUrl string, queryString []KV) (string, error) {
+	return o.DoOauthRequestContext(context.Background(), verb, requestUrl, queryString)
+}
 
-	authHeader, err := o.GetOAuthHeader(verb, requestUrl, queryString)
+// DoOauthRequestContext is DoOauthRequest over a context, so the
+// request can be canceled or given a deadline:
+//
+//  ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+//  defer cancel()
+//  body, err := o.DoOauthRequestContext(ctx, "GET", u, nil)
+func (o *OAuthParameters) DoOauthRequestContext(ctx context.Context, verb string, requestUrl string, queryString []KV) (string, error) {
+	resp, err := o.Do(ctx, verb, requestUrl, queryString)
 	if err != nil {
 		return "", err
 	}
+	defer resp.Body.Close()
+	max := o.MaxResponseSize
+	if max <= 0 {
+		max = DefaultMaxResponseSize
+	}
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, max+1))
+	if err != nil {
+		return "", err
+	}
+	if int64(len(body)) > max {
+		return "", ErrResponseTooLarge
+	}
+	return string(body), nil
+}
+
+// Do sends a signed request, returning the response of the server
+// whatever its status, so callers can tell a rejected signature
+// (401) from a server error. The caller must close the body of the
+// response:
+//
+//  resp, err := o.Do(ctx, "GET", u, nil)
+//  if err != nil {
+//    return err
+//  }
+//  defer resp.Body.Close()
+//  if resp.StatusCode == http.StatusUnauthorized {
+//    ...
+//  }
+func (o *OAuthParameters) Do(ctx context.Context, verb string, requestUrl string, queryString []KV) (*http.Response, error) {
+	return o.DoWithBody(ctx, verb, requestUrl, queryString, "", nil)
+}
+
+// DoWithBody is Do sending body with the contentType given, like
+// the XML of an outcomes call:
+//
+//  resp, err := o.DoWithBody(ctx, "POST", u, nil, "application/xml", xml)
+func (o *OAuthParameters) DoWithBody(ctx context.Context, verb string, requestUrl string, queryString []KV, contentType string, body []byte) (*http.Response, error) {
+	return o.do(ctx, func(ctx context.Context, signer *OAuthParameters) (*http.Request, error) {
+		return signer.NewRequest(ctx, verb, requestUrl, queryString, contentType, body)
+	})
+}
+
+// DoWithReader is DoWithBody streaming the body from r, which is
+// read twice, hashing it for oauth_body_hash and sending it, so
+// large bodies like multipart file submissions are not held in
+// memory:
+//
+//  f, err := os.Open(path)
+//  ...
+//  resp, err := o.DoWithReader(ctx, "POST", u, nil, w.FormDataContentType(), f)
+func (o *OAuthParameters) DoWithReader(ctx context.Context, verb string, requestUrl string, queryString []KV, contentType string, r io.ReadSeeker) (*http.Response, error) {
+	return o.do(ctx, func(ctx context.Context, signer *OAuthParameters) (*http.Request, error) {
+		return signer.NewStreamRequest(ctx, verb, requestUrl, queryString, contentType, r)
+	})
+}
+
+// attemptContext returns the context of an attempt of a request,
+// limited by Timeout.
+func (o *OAuthParameters) attemptContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	t := o.Timeout
+	if t == 0 {
+		t = DefaultTimeout
+	}
+	if t < 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, t)
+}
+
+// cancelBody cancels the context of its request when closed.
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// do sends the requests returned by newRequest for each attempt,
+// each one limited by Timeout. The body of the response returned
+// ends its attempt when closed.
+func (o *OAuthParameters) do(ctx context.Context, newRequest func(ctx context.Context, signer *OAuthParameters) (*http.Request, error)) (*http.Response, error) {
+	c := o.Client
+	if c == nil {
+		c = http.DefaultClient
+	}
+	attempts := 1
+	if o.Retry != nil && o.Retry.MaxAttempts > 1 {
+		attempts = o.Retry.MaxAttempts
+	}
+	signer := o
+	for n := 1; ; n++ {
+		actx, cancel := o.attemptContext(ctx)
+		req, err := newRequest(actx, signer)
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		resp, err := c.Do(req)
+		if n == attempts || ctx.Err() != nil || err == nil && resp.StatusCode < 500 {
+			if err != nil {
+				cancel()
+				return nil, err
+			}
+			resp.Body = &cancelBody{resp.Body, cancel}
+			return resp, nil
+		}
+		if resp != nil {
+			io.Copy(ioutil.Discard, io.LimitReader(resp.Body, maxDrain))
+			resp.Body.Close()
+		}
+		cancel()
+		t := time.NewTimer(o.Retry.wait(n))
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return nil, ctx.Err()
+		case <-t.C:
+		}
+		// a fresh nonce and timestamp, so the retry isn't a replay
+		retry := *o
+		retry.Nonce, retry.Timestamp = nil, nil
+		signer = &retry
+	}
+}
+
+// authorization returns the Authorization header of a request with
+// body, signing the params of a form body, or the oauth_body_hash of
+// any other body.
+func (o *OAuthParameters) authorization(verb, requestUrl string, queryString []KV, contentType string, body []byte) (string, error) {
+	signed := o
+	params := queryString
+	if ct, _, _ := mime.ParseMediaType(contentType); ct == "application/x-www-form-urlencoded" {
+		form, err := url.ParseQuery(string(body))
+		if err != nil {
+			return "", err
+		}
+		params = append(append([]KV(nil), queryString...), KVsFromValues(form)...)
+	} else if len(body) > 0 {
+		withBody := *o
+		withBody.SetBody(body)
+		signed = &withBody
+	}
+	return signed.GetOAuthHeader(verb, requestUrl, params)
+}
+
+// NewRequest returns a signed request with body, if any. The params
+// of a form body are signed, any other body is signed with its
+// oauth_body_hash.
+func (o *OAuthParameters) NewRequest(ctx context.Context, verb string, requestUrl string, queryString []KV, contentType string, body []byte) (*http.Request, error) {
+	authHeader, err := o.authorization(verb, requestUrl, queryString, contentType, body)
+	if err != nil {
+		return nil, err
+	}
+
+	var r io.Reader
+	if body != nil {
+		r = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, verb, fullURL(requestUrl, queryString), r)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Add("Authorization", authHeader)
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	return req, nil
+}
+
+// NewStreamRequest is NewRequest streaming the body from r, signed
+// with its oauth_body_hash whatever its content type. r is read from
+// its start, twice.
+func (o *OAuthParameters) NewStreamRequest(ctx context.Context, verb string, requestUrl string, queryString []KV, contentType string, r io.ReadSeeker) (*http.Request, error) {
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	withBody := *o
+	if err := withBody.SetBodyReader(r); err != nil {
+		return nil, err
+	}
+	size, err := r.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	authHeader, err := withBody.GetOAuthHeader(verb, requestUrl, queryString)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, verb, fullURL(requestUrl, queryString), ioutil.NopCloser(r))
+	if err != nil {
+		return nil, err
+	}
+	req.ContentLength = size
+	req.Header.Add("Authorization", authHeader)
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	return req, nil
+}
 
+// fullURL returns requestUrl with the queryString.
+func fullURL(requestUrl string, queryString []KV) string {
 	qsParams := make([]string, len(queryString), len(queryString))
 	for i, kv := range queryString {
-		qsParams[i] = url.QueryEscape(kv.Key) + "=" + url.QueryEscape(kv.Val)
+		qsParams[i] = Encode(kv.Key) + "=" + Encode(kv.Val)
 	}
 
-	fullUrl := requestUrl
 	if len(qsParams) > 0 {
-		fullUrl = fullUrl + "?" + strings.Join(qsParams, "&")
+		return requestUrl + "?" + strings.Join(qsParams, "&")
 	}
+	return requestUrl
+}
+
+// Transport is an http.RoundTripper signing every request with the
+// credentials of Params, so any http.Client can send OAuth signed
+// requests:
+//
+//  c := &http.Client{Transport: &oauth.Transport{Params: o}}
+//
+// Each request is signed with a fresh nonce and timestamp. The params
+// of form bodies are signed, any other body is signed with its
+// oauth_body_hash.
+type Transport struct {
+	Params *OAuthParameters
+	// Base sends the signed requests, http.DefaultTransport if nil.
+	Base http.RoundTripper
+}
 
-	req, err := http.NewRequest(verb, fullUrl, nil)
+// RoundTrip signs a copy of req and sends it.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ct, _, _ := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	if req.GetBody != nil && req.Body != nil && req.Body != http.NoBody && ct != "application/x-www-form-urlencoded" {
+		return t.roundTripStream(req)
+	}
+	var body []byte
+	if req.Body != nil && req.Body != http.NoBody {
+		var err error
+		body, err = ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	r := req.Clone(req.Context())
+	r.Header.Del("Authorization")
+	if body != nil {
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+		r.ContentLength = int64(len(body))
+	}
+	collected, err := CollectParameters(r)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
+	u := *req.URL
+	u.RawQuery = ""
 
-	req.Header.Add("Authorization", authHeader)
-
-	c := http.DefaultClient
+	params := *t.Params
+	params.Nonce, params.Timestamp = nil, nil
+	if ct != "application/x-www-form-urlencoded" && len(body) > 0 {
+		params.SetBody(body)
+	}
+	auth, err := params.GetOAuthHeader(req.Method, u.String(), collected)
+	if err != nil {
+		return nil, err
+	}
+	r.Header.Set("Authorization", auth)
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return base.RoundTrip(r)
+}
 
-	resp, err := c.Do(req)
+// roundTripStream signs req with the oauth_body_hash of a copy of
+// its body from GetBody, sending the body as a stream. As a
+// RoundTripper must, the body of req is closed when it fails before
+// sending it.
+func (t *Transport) roundTripStream(req *http.Request) (*http.Response, error) {
+	r, err := t.signStream(req)
 	if err != nil {
-		return "", err
+		req.Body.Close()
+		return nil, err
 	}
-	body, err := ioutil.ReadAll(resp.Body)
-	return string(body), nil
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return base.RoundTrip(r)
+}
 
+// signStream returns a copy of req signed by roundTripStream.
+func (t *Transport) signStream(req *http.Request) (*http.Request, error) {
+	params := *t.Params
+	params.Nonce, params.Timestamp = nil, nil
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, err
+	}
+	err = params.SetBodyReader(body)
+	body.Close()
+	if err != nil {
+		return nil, err
+	}
+	r := req.Clone(req.Context())
+	r.Header.Del("Authorization")
+	collected, err := CollectParameters(r)
+	if err != nil {
+		return nil, err
+	}
+	u := *req.URL
+	u.RawQuery = ""
+	auth, err := params.GetOAuthHeader(req.Method, u.String(), collected)
+	if err != nil {
+		return nil, err
+	}
+	r.Header.Set("Authorization", auth)
+	return r, nil
 }
@@ -0,0 +1,75 @@
+// Package oauthtest provides conformance tests for the
+// implementations of the interfaces of the oauth package, kept out
+// of it so its production builds don't import testing.
+package oauthtest
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/jordic/lti/oauth"
+)
+
+// signerBaseStrings are the base strings TestSigner signs, covering
+// the edge cases a signer must handle.
+var signerBaseStrings = []string{
+	"",
+	"GET&http%3A%2F%2Fexample.com%2F&",
+	"POST&http%3A%2F%2Fexample.com%2Flaunch&a%3D1%26b%3D%2520%252B",
+	"POST&https%3A%2F%2Fexample.com%2F&name%3D%25E2%2598%2583",
+	strings.Repeat("POST&http%3A%2F%2Fexample.com%2F&a%3Db", 1000),
+}
+
+// TestSigner is a conformance test for oauth.OauthSigner
+// implementations. It checks the method name, and that every
+// signature produced verifies with verify, while a signature of
+// another base string doesn't, except for PLAINTEXT, whose signature
+// doesn't depend on the base string. When verify is nil, signatures
+// are verified by signing again, which only works for deterministic
+// methods like HMAC:
+//
+//  func TestMySigner(t *testing.T) {
+//    oauthtest.TestSigner(t, NewMySigner(key), myVerify)
+//  }
+func TestSigner(t testing.TB, s oauth.OauthSigner, verify func(baseString, signature string) error) {
+	t.Helper()
+
+	m := s.GetMethod()
+	if m == "" || strings.ToUpper(m) != m || strings.ContainsAny(m, " ,\"") {
+		t.Errorf("Method should be an upper case token, got %q", m)
+	}
+
+	if verify == nil {
+		verify = func(baseString, signature string) error {
+			sig, err := s.GetSignature(baseString)
+			if err != nil {
+				return err
+			}
+			if sig != signature {
+				return errors.New("signature mismatch")
+			}
+			return nil
+		}
+	}
+
+	for i, bs := range signerBaseStrings {
+		sig, err := s.GetSignature(bs)
+		if err != nil {
+			t.Errorf("Error signing base string %d: %s", i, err)
+			continue
+		}
+		if sig == "" {
+			t.Errorf("Empty signature for base string %d", i)
+		}
+		if err := verify(bs, sig); err != nil {
+			t.Errorf("Signature of base string %d doesn't verify: %s", i, err)
+		}
+		if m == oauth.PLAINTEXT {
+			continue
+		}
+		if err := verify(bs+"x", sig); err == nil {
+			t.Errorf("Signature of base string %d verifies another base string", i)
+		}
+	}
+}
@@ -0,0 +1,21 @@
+package oauthtest
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+
+	"github.com/jordic/lti/oauth"
+)
+
+func TestSignerConformance(t *testing.T) {
+	TestSigner(t, oauth.GetHMACSigner("secret", "token"), nil)
+	TestSigner(t, oauth.GetHMAC256Signer("secret", "token"), nil)
+	TestSigner(t, oauth.GetPlaintextSigner("secret", "token"), nil)
+
+	pk, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Error generating key %s", err)
+	}
+	TestSigner(t, oauth.GetRSASigner(pk), oauth.GetRSAVerifier(&pk.PublicKey).Verify)
+}
@@ -0,0 +1,94 @@
+package oauth
+
+import (
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"io/ioutil"
+)
+
+// ParseRSAPrivateKey parses the first PEM block of data, an RSA
+// private key in PKCS#1 ("RSA PRIVATE KEY") or PKCS#8 ("PRIVATE
+// KEY") form.
+func ParseRSAPrivateKey(data []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, ErrF("no PEM data found")
+	}
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		k, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		pk, ok := k.(*rsa.PrivateKey)
+		if !ok {
+			return nil, ErrF("not an RSA private key")
+		}
+		return pk, nil
+	}
+	return nil, ErrF("unexpected PEM block %s", block.Type)
+}
+
+// ParseRSAPublicKey parses the first PEM block of data, a
+// certificate ("CERTIFICATE") or a PKIX public key ("PUBLIC KEY")
+// holding an RSA key, as consumers publish their keys.
+func ParseRSAPublicKey(data []byte) (*rsa.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, ErrF("no PEM data found")
+	}
+	var k interface{}
+	switch block.Type {
+	case "CERTIFICATE":
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		k = cert.PublicKey
+	case "PUBLIC KEY":
+		var err error
+		if k, err = x509.ParsePKIXPublicKey(block.Bytes); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, ErrF("unexpected PEM block %s", block.Type)
+	}
+	pk, ok := k.(*rsa.PublicKey)
+	if !ok {
+		return nil, ErrF("not an RSA public key")
+	}
+	return pk, nil
+}
+
+// LoadRSASigner returns an RSA-SHA1 signer with the private key of
+// the PEM file at path:
+//
+//  s, err := oauth.LoadRSASigner("/etc/tool/key.pem")
+func LoadRSASigner(path string) (*RSASigner, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	pk, err := ParseRSAPrivateKey(data)
+	if err != nil {
+		return nil, err
+	}
+	return GetRSASigner(pk), nil
+}
+
+// LoadRSAVerifier returns an RSA-SHA1 verifier with the public key
+// of the certificate or key PEM file at path.
+func LoadRSAVerifier(path string) (*RSAVerifier, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	pk, err := ParseRSAPublicKey(data)
+	if err != nil {
+		return nil, err
+	}
+	return GetRSAVerifier(pk), nil
+}
@@ -0,0 +1,215 @@
+package oauth
+
+import (
+	"bytes"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// VerifyRequest verifies the OAuth signature of the request r
+// received by a server, with the secrets returned by lookupSecret
+// for its consumer key. The params signed are collected from the
+// query, the form body and the Authorization header. When r has an
+// oauth_body_hash, it is checked against the body, which is read
+// and restored. HMAC-SHA1 and HMAC-SHA256 signatures are accepted,
+// PLAINTEXT ones only with a RequestVerifier allowing them:
+//
+//  err := oauth.VerifyRequest(r, func(key string) (string, string, error) {
+//    return secrets[key], "", nil
+//  })
+//
+// Only the signature is checked, the timestamp and nonce are left to
+// the caller. Behind a proxy, r.URL must be set to the URL the
+// client signed.
+func VerifyRequest(r *http.Request, lookupSecret func(consumerKey string) (clientSecret, tokenSecret string, err error)) error {
+	return (&RequestVerifier{}).Verify(r, lookupSecret)
+}
+
+// RequestVerifier verifies the requests received by a server as
+// VerifyRequest does, with the options set.
+type RequestVerifier struct {
+	// AllowPlaintext makes Verify accept PLAINTEXT signatures,
+	// that are the secrets, on the requests received over TLS.
+	AllowPlaintext bool
+	// TrustProxy makes Verify take the scheme and host of the URL
+	// signed from the X-Forwarded-Proto and X-Forwarded-Host
+	// headers. They are set by the client, so it must only be
+	// enabled behind a proxy that overwrites them.
+	TrustProxy bool
+}
+
+// Verify verifies the OAuth signature of the request r, as
+// VerifyRequest does.
+func (rv *RequestVerifier) Verify(r *http.Request, lookupSecret func(consumerKey string) (clientSecret, tokenSecret string, err error)) error {
+	params, err := CollectParameters(r)
+	if err != nil {
+		return err
+	}
+	var signature, key, method, bodyHash string
+	for _, kv := range params {
+		switch kv.Key {
+		case "oauth_signature":
+			signature = kv.Val
+		case "oauth_consumer_key":
+			key = kv.Val
+		case "oauth_signature_method":
+			method = kv.Val
+		case "oauth_body_hash":
+			bodyHash = kv.Val
+		}
+	}
+	if key == "" || signature == "" {
+		return ErrF("oauth_consumer_key and oauth_signature are required")
+	}
+
+	clientSecret, tokenSecret, err := lookupSecret(key)
+	if err != nil {
+		return err
+	}
+	var v OauthVerifier
+	switch method {
+	case HMACSHA1:
+		v = GetHMACSigner(clientSecret, tokenSecret)
+	case HMACSHA256:
+		v = GetHMAC256Signer(clientSecret, tokenSecret)
+	case PLAINTEXT:
+		if !rv.AllowPlaintext || r.TLS == nil {
+			return ErrF("PLAINTEXT signatures are only accepted over https when allowed")
+		}
+		v = GetPlaintextSigner(clientSecret, tokenSecret)
+	default:
+		return ErrF("unsupported signature method %q", method)
+	}
+
+	base, err := GetBaseString(r.Method, RequestURL(r, rv.TrustProxy), params)
+	if err != nil {
+		return err
+	}
+	if err := v.Verify(base, signature); err != nil {
+		return err
+	}
+	if bodyHash != "" {
+		return CheckBodyHash(r, method, bodyHash)
+	}
+	return nil
+}
+
+// CollectParameters returns the params of r, as RFC 5849 section
+// 3.4.1.3.1 says: the query, the form body, only when its content
+// type is application/x-www-form-urlencoded, and the OAuth
+// Authorization header params but the realm. oauth_signature is
+// kept, GetBaseString leaves it out. It works on the requests sent
+// and received alike: a form body already parsed is taken from
+// r.PostForm, otherwise it is read and restored.
+func CollectParameters(r *http.Request) ([]KV, error) {
+	var params []KV
+	if r.URL != nil {
+		params = KVsFromValues(r.URL.Query())
+	}
+	if ct, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); ct == "application/x-www-form-urlencoded" {
+		form := r.PostForm
+		if form == nil && r.Body != nil {
+			body, err := ioutil.ReadAll(r.Body)
+			r.Body.Close()
+			if err != nil {
+				return nil, err
+			}
+			r.Body = ioutil.NopCloser(bytes.NewReader(body))
+			if form, err = url.ParseQuery(string(body)); err != nil {
+				return nil, err
+			}
+		}
+		params = append(params, KVsFromValues(form)...)
+	}
+	if h := r.Header.Get("Authorization"); len(h) > 6 && strings.EqualFold(h[:6], "OAuth ") {
+		kvs, _, err := ParseAuthorizationHeader(h)
+		if err != nil {
+			return nil, err
+		}
+		params = append(params, kvs...)
+	}
+	return params, nil
+}
+
+// RequestURL returns the URL the request r was sent to, without its
+// query, as signed by the client. Behind a proxy, when trustProxy is
+// set, the scheme and host are taken from the X-Forwarded-Proto and
+// X-Forwarded-Host headers, that are only trustworthy when the proxy
+// overwrites them.
+func RequestURL(r *http.Request, trustProxy bool) string {
+	u := url.URL{Scheme: "http", Host: r.Host}
+	if r.TLS != nil {
+		u.Scheme = "https"
+	}
+	if r.URL != nil && r.URL.IsAbs() {
+		u.Scheme, u.Host = r.URL.Scheme, r.URL.Host
+	}
+	if trustProxy {
+		if proto := forwarded(r, "X-Forwarded-Proto"); proto != "" {
+			u.Scheme = proto
+		}
+		if host := forwarded(r, "X-Forwarded-Host"); host != "" {
+			u.Host = host
+		}
+	}
+	if r.URL != nil {
+		if u.Host == "" {
+			u.Host = r.URL.Host
+		}
+		u.Path = r.URL.Path
+	}
+	return u.String()
+}
+
+// IsHTTPS tells if the request r was received over https: r.TLS is
+// set or, behind a proxy when trustProxy is set, X-Forwarded-Proto
+// says so.
+func IsHTTPS(r *http.Request, trustProxy bool) bool {
+	if r.TLS != nil {
+		return true
+	}
+	return trustProxy && strings.EqualFold(forwarded(r, "X-Forwarded-Proto"), "https")
+}
+
+// forwarded returns the first value of a X-Forwarded-* header,
+// the one set by the proxy nearest to the client.
+func forwarded(r *http.Request, h string) string {
+	v := r.Header.Get(h)
+	if i := strings.Index(v, ","); i >= 0 {
+		v = v[:i]
+	}
+	return strings.TrimSpace(v)
+}
+
+// BodyHashError is returned by CheckBodyHash when the body doesn't
+// match the oauth_body_hash claimed.
+type BodyHashError struct {
+	Claimed, Computed string
+}
+
+func (e *BodyHashError) Error() string {
+	return "oauth_body_hash " + e.Claimed + " doesn't match the body, expected " + e.Computed
+}
+
+// CheckBodyHash checks the body of r against the oauth_body_hash
+// claimed, hashed as the signature method says, restoring the body
+// so it can still be read. A mismatch is a *BodyHashError.
+func CheckBodyHash(r *http.Request, method, claimed string) error {
+	var body []byte
+	if r.Body != nil {
+		var err error
+		body, err = ioutil.ReadAll(r.Body)
+		r.Body.Close()
+		if err != nil {
+			return err
+		}
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+	if hash := BodyHash(body, BodyHashAlgorithm(method)); hash != claimed {
+		return &BodyHashError{Claimed: claimed, Computed: hash}
+	}
+	return nil
+}
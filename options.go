@@ -0,0 +1,207 @@
+package lti
+
+import (
+	"time"
+
+	"github.com/jordic/lti/oauth"
+)
+
+// Option configures a Provider created with NewProvider.
+//
+//  p := lti.NewProvider("secret", "http://url.com",
+//    lti.WithConsumerKey("12345"),
+//    lti.WithMethod("GET"))
+type Option func(*Provider)
+
+// WithSigner sets the signer used to sign and verify requests,
+// instead of the default HMAC-SHA1 signer.
+func WithSigner(s oauth.OauthSigner) Option {
+	return func(p *Provider) {
+		p.Signer = s
+	}
+}
+
+// WithConsumerKey sets the consumer key of the provider.
+func WithConsumerKey(key string) Option {
+	return func(p *Provider) {
+		p.ConsumerKey = key
+	}
+}
+
+// WithMethod sets the http method used when signing requests.
+func WithMethod(method string) Option {
+	return func(p *Provider) {
+		p.Method = method
+	}
+}
+
+// WithURLPolicy sets the URLPolicy applied when verifying launches.
+func WithURLPolicy(policy URLPolicy) Option {
+	return func(p *Provider) {
+		p.URLPolicy = policy
+	}
+}
+
+// WithFailureStore sets the store where verification failures are
+// recorded.
+func WithFailureStore(s FailureStore) Option {
+	return func(p *Provider) {
+		p.Failures = s
+	}
+}
+
+// WithDetectURL makes IsValid derive the launch URL from the
+// request, see Provider.DetectURL.
+func WithDetectURL() Option {
+	return func(p *Provider) {
+		p.DetectURL = true
+	}
+}
+
+// WithTrustProxy makes IsValid trust the X-Forwarded-Proto and
+// X-Forwarded-Host headers, see Provider.TrustProxy.
+func WithTrustProxy() Option {
+	return func(p *Provider) {
+		p.TrustProxy = true
+	}
+}
+
+// WithAllowGET makes IsValid accept launches sent as GET.
+func WithAllowGET() Option {
+	return func(p *Provider) {
+		p.AllowGET = true
+	}
+}
+
+// WithNonceStore sets the store used to reject replayed requests.
+func WithNonceStore(s NonceStore) Option {
+	return func(p *Provider) {
+		p.Nonces = s
+	}
+}
+
+// WithAllowOAuth10a makes IsValid accept oauth_version 1.0a.
+func WithAllowOAuth10a() Option {
+	return func(p *Provider) {
+		p.AllowOAuth10a = true
+	}
+}
+
+// WithMaxFormSize limits the size of the request bodies read by
+// IsValid to n bytes.
+func WithMaxFormSize(n int64) Option {
+	return func(p *Provider) {
+		p.MaxFormSize = n
+	}
+}
+
+// WithDebug logs the debug output of the provider to l, or to the
+// standard logger when l is nil.
+func WithDebug(l Logger) Option {
+	return func(p *Provider) {
+		p.Debug = true
+		p.Logger = l
+	}
+}
+
+// WithPrivacy sets what IsValid does with the personal data of
+// the user.
+func WithPrivacy(m PrivacyMode) Option {
+	return func(p *Provider) {
+		p.Privacy = m
+	}
+}
+
+// WithPrivacyHash makes IsValid replace the personal data of the
+// user with an HMAC-SHA256 of it keyed with key.
+func WithPrivacyHash(key []byte) Option {
+	return func(p *Provider) {
+		p.Privacy = PrivacyHash
+		p.PrivacyKey = key
+	}
+}
+
+// WithTokenKey sets the key of the HMAC of the launch tokens. An
+// empty key is ignored, leaving the launch tokens disabled.
+func WithTokenKey(key []byte) Option {
+	return func(p *Provider) {
+		if len(key) > 0 {
+			p.TokenKey = key
+		}
+	}
+}
+
+// WithExtraOAuthPolicy sets what IsValid does with unexpected oauth_
+// params.
+func WithExtraOAuthPolicy(pol ExtraOAuthPolicy) Option {
+	return func(p *Provider) {
+		p.ExtraOAuth = pol
+	}
+}
+
+// WithLenientVersion makes ValidateLaunch accept any lti_version.
+func WithLenientVersion() Option {
+	return func(p *Provider) {
+		p.LenientVersion = true
+	}
+}
+
+// WithClockSkew enables the check of the oauth_timestamp of the
+// requests, setting how far it can be from the current time.
+func WithClockSkew(d time.Duration) Option {
+	return func(p *Provider) {
+		p.ClockSkew = d
+	}
+}
+
+// WithStrictMode runs every conformance check on validation.
+func WithStrictMode() Option {
+	return func(p *Provider) {
+		p.StrictMode = true
+	}
+}
+
+// WithSecretStore sets the store of the consumer secrets used by
+// IsValid.
+func WithSecretStore(s SecretStore) Option {
+	return func(p *Provider) {
+		p.Secrets = s
+	}
+}
+
+// WithUnicodeNormalization signs the params normalized to NFC.
+func WithUnicodeNormalization() Option {
+	return func(p *Provider) {
+		p.NormalizeUnicode = true
+	}
+}
+
+// WithAllowPlaintext makes IsValid accept PLAINTEXT signatures over
+// https.
+func WithAllowPlaintext() Option {
+	return func(p *Provider) {
+		p.AllowPlaintext = true
+	}
+}
+
+// WithVerifier sets the Verifier of the launches, like an
+// oauth.RSAVerifier for consumers signing with RSA.
+func WithVerifier(v oauth.OauthVerifier) Option {
+	return func(p *Provider) {
+		p.Verifier = v
+	}
+}
+
+// WithNonceSource sets the NonceSource of the signed requests.
+func WithNonceSource(s oauth.NonceSource) Option {
+	return func(p *Provider) {
+		p.NonceSource = s
+	}
+}
+
+// WithClock sets the Clock used signing and checking timestamps.
+func WithClock(c oauth.Clock) Option {
+	return func(p *Provider) {
+		p.Clock = c
+	}
+}
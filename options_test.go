@@ -0,0 +1,23 @@
+package lti
+
+import (
+	"testing"
+
+	"github.com/jordic/lti/oauth"
+)
+
+func TestNewProviderOptions(t *testing.T) {
+	s := oauth.GetHMACSigner("other", "")
+	p := NewProvider("secret", "http://localhost",
+		WithSigner(s),
+		WithConsumerKey("12345"),
+		WithMethod("GET"),
+		WithURLPolicy(URLIgnoreTrailingSlash))
+
+	if p.Signer != s {
+		t.Error("Signer should be set")
+	}
+	if p.ConsumerKey != "12345" || p.Method != "GET" || p.URLPolicy != URLIgnoreTrailingSlash {
+		t.Errorf("Options not applied %s %s %d", p.ConsumerKey, p.Method, p.URLPolicy)
+	}
+}
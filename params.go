@@ -0,0 +1,148 @@
+package lti
+
+import (
+	"strconv"
+	"strings"
+)
+
+// CustomParams returns the custom_* params of the launch, with the
+// prefix stripped. Names are normalized as the LTI spec asks the
+// consumers to do: lowercased, and any char not a letter or a
+// number replaced by an underscore, so "custom_Review Chapter" is
+// returned as "review_chapter".
+func (p *Provider) CustomParams() map[string]string {
+	return p.prefixed("custom_", normalizeParamName)
+}
+
+// ExtParams returns the ext_* vendor extension params of the
+// launch, with the prefix stripped.
+func (p *Provider) ExtParams() map[string]string {
+	return p.prefixed("ext_", func(n string) string { return n })
+}
+
+// Extensions holds the vendor extension params most LMSes send.
+type Extensions struct {
+	// UserUsername is the login of the user in the LMS.
+	UserUsername string
+	// LMS identifies the LMS sending the launch (moodle-2, ...).
+	LMS string
+}
+
+// Extensions returns the common vendor extensions of the launch.
+func (p *Provider) Extensions() Extensions {
+	return Extensions{
+		UserUsername: p.Get("ext_user_username"),
+		LMS:          p.Get("ext_lms"),
+	}
+}
+
+// Person holds the user data of a launch, sent by the consumer
+// depending on its privacy settings.
+type Person struct {
+	GivenName  string
+	FamilyName string
+	FullName   string
+	Email      string
+	// SourcedID is the id of the user in the SIS of the
+	// institution.
+	SourcedID string
+}
+
+// Person returns the user data of the launch, from its lis_person_
+// params. The fields not sent are empty.
+func (p *Provider) Person() Person {
+	return Person{
+		GivenName:  p.Get("lis_person_name_given"),
+		FamilyName: p.Get("lis_person_name_family"),
+		FullName:   p.Get("lis_person_name_full"),
+		Email:      p.Get("lis_person_contact_email_primary"),
+		SourcedID:  p.Get("lis_person_sourcedid"),
+	}
+}
+
+// ToolConsumer identifies the LMS instance sending a launch.
+type ToolConsumer struct {
+	// InstanceGUID is unique for every LMS instance.
+	InstanceGUID string
+	// ProductFamilyCode is the kind of LMS (moodle, canvas, ...).
+	ProductFamilyCode string
+	Version           string
+	Description       string
+	ContactEmail      string
+}
+
+// ToolConsumer returns the LMS instance of the launch, from its
+// tool_consumer_ params.
+func (p *Provider) ToolConsumer() ToolConsumer {
+	return ToolConsumer{
+		InstanceGUID:      p.Get("tool_consumer_instance_guid"),
+		ProductFamilyCode: p.Get("tool_consumer_info_product_family_code"),
+		Version:           p.Get("tool_consumer_info_version"),
+		Description:       p.Get("tool_consumer_instance_description"),
+		ContactEmail:      p.Get("tool_consumer_instance_contact_email"),
+	}
+}
+
+// LaunchPresentation tells how the tool is shown by the consumer.
+type LaunchPresentation struct {
+	// DocumentTarget is frame, iframe or window.
+	DocumentTarget string
+	ReturnURL      string
+	CSSURL         string
+	// Locale is the locale sent, like en-US or en_US, and Language
+	// and Region its parts, as lower and upper case.
+	Locale   string
+	Language string
+	Region   string
+	// Width and Height are the size of the frame or window, 0 when
+	// not sent.
+	Width  int
+	Height int
+}
+
+// LaunchPresentation returns the presentation of the launch, from
+// its launch_presentation_ params.
+func (p *Provider) LaunchPresentation() LaunchPresentation {
+	lp := LaunchPresentation{
+		DocumentTarget: p.Get("launch_presentation_document_target"),
+		ReturnURL:      p.Get("launch_presentation_return_url"),
+		CSSURL:         p.Get("launch_presentation_css_url"),
+		Locale:         p.Get("launch_presentation_locale"),
+	}
+	lp.Width, _ = strconv.Atoi(p.Get("launch_presentation_width"))
+	lp.Height, _ = strconv.Atoi(p.Get("launch_presentation_height"))
+
+	parts := strings.FieldsFunc(lp.Locale, func(r rune) bool { return r == '-' || r == '_' })
+	if len(parts) > 0 {
+		lp.Language = strings.ToLower(parts[0])
+	}
+	if len(parts) > 1 {
+		lp.Region = strings.ToUpper(parts[1])
+	}
+	return lp
+}
+
+// prefixed collects the params starting with prefix, with the
+// prefix stripped and the name passed through norm.
+func (p *Provider) prefixed(prefix string, norm func(string) string) map[string]string {
+	m := map[string]string{}
+	for k := range p.Params() {
+		if !strings.HasPrefix(k, prefix) || len(k) == len(prefix) {
+			continue
+		}
+		m[norm(k[len(prefix):])] = p.Get(k)
+	}
+	return m
+}
+
+func normalizeParamName(n string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			return r
+		case r >= 'A' && r <= 'Z':
+			return r + 'a' - 'A'
+		}
+		return '_'
+	}, n)
+}
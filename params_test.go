@@ -0,0 +1,105 @@
+package lti
+
+import "testing"
+
+func TestCustomParams(t *testing.T) {
+	p := NewProvider("asdf", "http://localhost")
+	p.Add("custom_username", "test").
+		Add("custom_Review Chapter", "1.2.56").
+		Add("custom_", "empty").
+		Add("context_id", "2")
+
+	c := p.CustomParams()
+	if len(c) != 2 {
+		t.Errorf("Expected 2 custom params, got %v", c)
+	}
+	if c["username"] != "test" {
+		t.Errorf("Wrong custom username %s", c["username"])
+	}
+	if c["review_chapter"] != "1.2.56" {
+		t.Errorf("Custom param name should be normalized, got %v", c)
+	}
+}
+
+func TestExtParams(t *testing.T) {
+	p := NewProvider("asdf", "http://localhost")
+	p.Add("ext_user_username", "jdoe").
+		Add("ext_lms", "moodle-2").
+		Add("ext_Other", "x").
+		Add("custom_username", "test")
+
+	e := p.ExtParams()
+	if len(e) != 3 || e["Other"] != "x" || e["lms"] != "moodle-2" {
+		t.Errorf("Wrong ext params %v", e)
+	}
+	ext := p.Extensions()
+	if ext.UserUsername != "jdoe" || ext.LMS != "moodle-2" {
+		t.Errorf("Wrong extensions %#v", ext)
+	}
+}
+
+func TestPerson(t *testing.T) {
+	p := NewProvider("secret", "http://localhost")
+	p.SetParams(GenerateForm())
+	expected := Person{
+		GivenName:  "Given",
+		FamilyName: "Public",
+		FullName:   "Jane Q. Public",
+		Email:      "user@school.edu",
+		SourcedID:  "school.edu:user",
+	}
+	if person := p.Person(); person != expected {
+		t.Errorf("Expected %+v, got %+v", expected, person)
+	}
+
+	if person := NewProvider("secret", "http://localhost").Person(); person != (Person{}) {
+		t.Errorf("Anonymous launch should get an empty Person, got %+v", person)
+	}
+}
+
+func TestToolConsumer(t *testing.T) {
+	p := NewProvider("secret", "http://localhost")
+	p.SetParams(GenerateForm())
+	p.Add("tool_consumer_instance_contact_email", "admin@school.edu")
+	expected := ToolConsumer{
+		InstanceGUID:      "lmsng.school.edu",
+		ProductFamilyCode: "ims",
+		Version:           "1.1",
+		Description:       "University of School (LMSng)",
+		ContactEmail:      "admin@school.edu",
+	}
+	if tc := p.ToolConsumer(); tc != expected {
+		t.Errorf("Expected %+v, got %+v", expected, tc)
+	}
+}
+
+func TestLaunchPresentation(t *testing.T) {
+	p := NewProvider("secret", "http://localhost")
+	p.SetParams(GenerateForm())
+	p.Add("launch_presentation_width", "640").
+		Add("launch_presentation_height", "none")
+	lp := p.LaunchPresentation()
+	expected := LaunchPresentation{
+		DocumentTarget: "frame",
+		ReturnURL:      "http://www.imsglobal.org/developers/LTI/test/v1p1/lms_return.php",
+		CSSURL:         "http://www.imsglobal.org/developers/LTI/test/v1p1/lms.css",
+		Locale:         "en-US",
+		Language:       "en",
+		Region:         "US",
+		Width:          640,
+	}
+	if lp != expected {
+		t.Errorf("Expected %+v, got %+v", expected, lp)
+	}
+
+	for locale, lang := range map[string][2]string{
+		"pt_br": {"pt", "BR"},
+		"ES":    {"es", ""},
+		"":      {"", ""},
+	} {
+		p.Add("launch_presentation_locale", locale)
+		if lp := p.LaunchPresentation(); lp.Language != lang[0] || lp.Region != lang[1] {
+			t.Errorf("Locale %s: expected %v, got %s %s", locale, lang, lp.Language, lp.Region)
+		}
+	}
+}
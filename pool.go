@@ -0,0 +1,124 @@
+package lti
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// ErrOverloaded is returned by VerifyPool when its queue is full.
+var ErrOverloaded = errors.New("too many launches being verified")
+
+// VerifyPool bounds the number of launches verified at the same
+// time, for tools receiving bursts of launches, like the start of an
+// exam. Up to workers launches are verified concurrently, up to queue
+// more wait for their turn, and the rest are rejected right away,
+// instead of piling up:
+//
+//  vp := lti.NewVerifyPool(p, 64, 1024)
+//  http.Handle("/launch", vp.Handler(launchHandler))
+//
+// A Provider verifies each launch with its own clone.
+type VerifyPool struct {
+	// RetryAfter is sent to rejected requests, in the Retry-After
+	// header. It defaults to a second.
+	RetryAfter time.Duration
+	// Hooks, when set, get the stats of the pool on every launch
+	// rejected or given a worker, see Hooks.OnPoolStats.
+	Hooks *Hooks
+
+	verifier LaunchVerifier
+	workers  chan struct{}
+	admitted chan struct{}
+	rejected int64
+}
+
+// PoolStats holds the state of a VerifyPool.
+type PoolStats struct {
+	// Verifying is the number of launches being verified.
+	Verifying int
+	// Queued is the number of launches waiting for a worker.
+	Queued int
+	// Rejected is the number of launches rejected since the pool
+	// was created.
+	Rejected int64
+}
+
+// NewVerifyPool returns a VerifyPool verifying launches with v.
+func NewVerifyPool(v LaunchVerifier, workers, queue int) *VerifyPool {
+	if workers < 1 {
+		workers = 1
+	}
+	if queue < 0 {
+		queue = 0
+	}
+	return &VerifyPool{
+		RetryAfter: time.Second,
+		verifier:   v,
+		workers:    make(chan struct{}, workers),
+		admitted:   make(chan struct{}, workers+queue),
+	}
+}
+
+// Verify verifies the launch r with the verifier of the pool,
+// returning the provider holding its params. It fails with
+// ErrOverloaded when the queue is full, or with the error of the
+// request context when it's done while waiting.
+func (vp *VerifyPool) Verify(r *http.Request) (*Provider, error) {
+	select {
+	case vp.admitted <- struct{}{}:
+	default:
+		atomic.AddInt64(&vp.rejected, 1)
+		vp.statsHook()
+		return nil, ErrOverloaded
+	}
+	defer func() { <-vp.admitted }()
+
+	select {
+	case vp.workers <- struct{}{}:
+	case <-r.Context().Done():
+		return nil, r.Context().Err()
+	}
+	defer func() { <-vp.workers }()
+	vp.statsHook()
+
+	return vp.verifier.Verify(r)
+}
+
+// Handler returns an http.Handler calling h with the verified
+// launches. It answers 429, with a Retry-After header, when the pool
+// is overloaded, and 401 with a generic body to invalid launches.
+func (vp *VerifyPool) Handler(h LaunchHandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p, err := vp.Verify(r)
+		switch {
+		case errors.Is(err, ErrOverloaded):
+			secs := int((vp.RetryAfter + time.Second - 1) / time.Second)
+			w.Header().Set("Retry-After", strconv.Itoa(secs))
+			http.Error(w, err.Error(), http.StatusTooManyRequests)
+			return
+		case err != nil:
+			http.Error(w, invalidLaunch, http.StatusUnauthorized)
+			return
+		}
+		h(w, r, p)
+	})
+}
+
+// Stats returns the current state of the pool.
+func (vp *VerifyPool) Stats() PoolStats {
+	verifying := len(vp.workers)
+	return PoolStats{
+		Verifying: verifying,
+		Queued:    len(vp.admitted) - verifying,
+		Rejected:  atomic.LoadInt64(&vp.rejected),
+	}
+}
+
+func (vp *VerifyPool) statsHook() {
+	if vp.Hooks != nil && vp.Hooks.OnPoolStats != nil {
+		vp.Hooks.OnPoolStats(vp.Stats())
+	}
+}
@@ -0,0 +1,87 @@
+package lti
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// blockingNonces holds every verification until released.
+type blockingNonces struct {
+	entered, release chan struct{}
+}
+
+func (b *blockingNonces) Seen(consumerKey, nonce string, ts time.Time) (bool, error) {
+	b.entered <- struct{}{}
+	<-b.release
+	return false, nil
+}
+
+func TestVerifyPool(t *testing.T) {
+	nonces := &blockingNonces{make(chan struct{}), make(chan struct{})}
+	p := NewProvider("asdf", "http://urltest.com/", WithConsumerKey("12345"),
+		WithNonceStore(nonces))
+	vp := NewVerifyPool(p, 1, 1)
+	vp.RetryAfter = 1500 * time.Millisecond
+	var mu sync.Mutex
+	var hooked []PoolStats
+	vp.Hooks = &Hooks{OnPoolStats: func(s PoolStats) {
+		mu.Lock()
+		defer mu.Unlock()
+		hooked = append(hooked, s)
+	}}
+	h := vp.Handler(func(w http.ResponseWriter, r *http.Request, p *Provider) {
+		w.Write([]byte(p.Get("resource_link_id")))
+	})
+
+	done := make(chan *httptest.ResponseRecorder, 2)
+	serve := func() {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, launchRequest(t, "http://urltest.com/", "Learner"))
+		done <- w
+	}
+	go serve()
+	<-nonces.entered
+	go serve()
+	for vp.Stats().Queued != 1 {
+		time.Sleep(time.Millisecond)
+	}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, launchRequest(t, "http://urltest.com/", "Learner"))
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") != "2" {
+		t.Errorf("Expected 429 with Retry-After 2, got %d %q", w.Code, w.Header().Get("Retry-After"))
+	}
+	if s := vp.Stats(); s != (PoolStats{Verifying: 1, Queued: 1, Rejected: 1}) {
+		t.Errorf("Unexpected stats %+v", s)
+	}
+	mu.Lock()
+	if len(hooked) != 2 || hooked[1] != (PoolStats{Verifying: 1, Queued: 1, Rejected: 1}) {
+		t.Errorf("Stats of the rejection should be hooked, got %+v", hooked)
+	}
+	mu.Unlock()
+
+	nonces.release <- struct{}{}
+	<-nonces.entered
+	nonces.release <- struct{}{}
+	for i := 0; i < 2; i++ {
+		if w := <-done; w.Code != http.StatusOK || w.Body.String() != "1" {
+			t.Errorf("Queued launch should be served, got %d %s", w.Code, w.Body)
+		}
+	}
+
+	w = httptest.NewRecorder()
+	r := launchRequest(t, "http://urltest.com/", "Learner")
+	r.Form = nil
+	r.Header.Set("Content-Type", "text/plain")
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Invalid launch should get 401, got %d", w.Code)
+	}
+	if body := strings.TrimSpace(w.Body.String()); body != invalidLaunch {
+		t.Errorf("Invalid launch should get a generic body, got %s", body)
+	}
+}
@@ -0,0 +1,85 @@
+package lti
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/url"
+	"strings"
+)
+
+// PrivacyMode tells what IsValid does with the personal data of
+// the user, once a launch is verified.
+type PrivacyMode int
+
+const (
+	// PrivacyKeep keeps the personal data in the params.
+	PrivacyKeep PrivacyMode = iota
+	// PrivacyStrip removes the personal data from the params.
+	PrivacyStrip
+	// PrivacyHash replaces the personal data with an HMAC-SHA256 of
+	// it, keyed with Provider.PrivacyKey, so the same user gets the
+	// same values without keeping them. Without a key the data is
+	// stripped, as unkeyed hashes of names and emails can be
+	// reversed with a dictionary.
+	PrivacyHash
+)
+
+// piiFields are the params of the LTI 1.1 launch holding personal
+// data of the user.
+var piiFields = []string{
+	"lis_person_name_given",
+	"lis_person_name_family",
+	"lis_person_name_full",
+	"lis_person_contact_email_primary",
+	"lis_person_sourcedid",
+	"user_image",
+	"ext_user_username",
+}
+
+// PIIFields returns the launch params defined by LTI 1.1 holding
+// personal data of the user. Besides them, IsPII reports every
+// lis_person_ param, and the ones holding email addresses.
+func PIIFields() []string {
+	return append([]string(nil), piiFields...)
+}
+
+// IsPII reports if the param k holds personal data of the user. It
+// is the list of PrivacyMode, and of the PII of RedactionPolicy.
+func IsPII(k string) bool {
+	for _, f := range piiFields {
+		if k == f {
+			return true
+		}
+	}
+	return strings.HasPrefix(k, "lis_person_") || strings.Contains(k, "email")
+}
+
+// applyPrivacy strips or hashes the personal data held by p,
+// following its Privacy mode.
+func (p *Provider) applyPrivacy() {
+	if p.Privacy == PrivacyKeep {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	hash := p.Privacy == PrivacyHash && len(p.PrivacyKey) > 0
+	// the params can be the form of the request, so they are
+	// copied instead of modified.
+	values := make(url.Values, len(p.values))
+	for k, vs := range p.values {
+		switch {
+		case !IsPII(k):
+			values[k] = vs
+		case hash:
+			hashed := make([]string, len(vs))
+			for i, v := range vs {
+				mac := hmac.New(sha256.New, p.PrivacyKey)
+				mac.Write([]byte(v))
+				hashed[i] = hex.EncodeToString(mac.Sum(nil))
+			}
+			values[k] = hashed
+		}
+	}
+	p.values = values
+}
@@ -0,0 +1,77 @@
+package lti
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"testing"
+)
+
+func TestPrivacy(t *testing.T) {
+	c := NewProvider("asdf", "http://urltest.com/", WithConsumerKey("12345"))
+	c.SetParams(GenerateForm())
+	c.Add("ext_user_email", "user@school.edu")
+	c.Params().Del("oauth_timestamp")
+	c.Params().Del("oauth_nonce")
+	c.Sign()
+
+	mac := hmac.New(sha256.New, []byte("privacy key"))
+	mac.Write([]byte("Jane Q. Public"))
+	hashed := hex.EncodeToString(mac.Sum(nil))
+
+	tests := []struct {
+		mode       PrivacyMode
+		opt        Option
+		name, mail string
+	}{
+		{PrivacyKeep, WithPrivacy(PrivacyKeep), "Jane Q. Public", "user@school.edu"},
+		{PrivacyStrip, WithPrivacy(PrivacyStrip), "", ""},
+		{PrivacyHash, WithPrivacyHash([]byte("privacy key")), hashed, "set"},
+		// without a key, hashing falls back to stripping
+		{PrivacyHash, WithPrivacy(PrivacyHash), "", ""},
+	}
+	for _, tt := range tests {
+		p := NewProvider("asdf", "http://urltest.com/", WithConsumerKey("12345"), tt.opt)
+		r := &http.Request{Method: "POST", Form: c.Params()}
+		if ok, err := p.IsValid(r); !ok {
+			t.Fatalf("Mode %d: launch should be valid, got %s", tt.mode, err)
+		}
+		if name := p.Get("lis_person_name_full"); name != tt.name {
+			t.Errorf("Mode %d: expected name %q, got %q", tt.mode, tt.name, name)
+		}
+		mail := p.Get("ext_user_email")
+		if tt.mail == "set" {
+			if mail == "" || mail == "user@school.edu" {
+				t.Errorf("Mode %d: email should be hashed, got %q", tt.mode, mail)
+			}
+		} else if mail != tt.mail {
+			t.Errorf("Mode %d: expected email %q, got %q", tt.mode, tt.mail, mail)
+		}
+		if p.Get("user_id") != "292832126" {
+			t.Errorf("Mode %d: user_id should be kept", tt.mode)
+		}
+		if r.Form.Get("lis_person_name_full") != "Jane Q. Public" {
+			t.Errorf("Mode %d: the request form should not be modified", tt.mode)
+		}
+	}
+}
+
+func TestIsPII(t *testing.T) {
+	for _, f := range PIIFields() {
+		if !IsPII(f) {
+			t.Errorf("%s should be PII", f)
+		}
+	}
+	for k, pii := range map[string]bool{
+		"lis_person_other":     true,
+		"custom_email":         true,
+		"user_id":              false,
+		"roles":                false,
+		"lis_result_sourcedid": false,
+	} {
+		if IsPII(k) != pii {
+			t.Errorf("IsPII(%s) should be %v", k, pii)
+		}
+	}
+}
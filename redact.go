@@ -0,0 +1,68 @@
+package lti
+
+import (
+	"net/url"
+	"strings"
+)
+
+// RedactedValue replaces the values masked by Redacted.
+const RedactedValue = "[redacted]"
+
+// RedactionPolicy tells which params Redacted masks.
+type RedactionPolicy struct {
+	// Fields are masked by exact name.
+	Fields []string
+	// Prefixes mask every param starting with them.
+	Prefixes []string
+	// PII masks the personal data of the user, the params IsPII
+	// reports.
+	PII bool
+}
+
+// DefaultRedactionPolicy masks the signature, the result sourced id
+// and the personal data of the user.
+var DefaultRedactionPolicy = RedactionPolicy{
+	Fields: []string{
+		"oauth_signature",
+		"lis_result_sourcedid",
+	},
+	PII: true,
+}
+
+func (rp RedactionPolicy) masks(k string) bool {
+	if rp.PII && IsPII(k) {
+		return true
+	}
+	for _, f := range rp.Fields {
+		if k == f {
+			return true
+		}
+	}
+	for _, pre := range rp.Prefixes {
+		if strings.HasPrefix(k, pre) {
+			return true
+		}
+	}
+	return false
+}
+
+// Redacted returns a copy of the params of the provider with the
+// fields of the Redaction policy masked, or the DefaultRedactionPolicy
+// if not set, safe to be logged:
+//
+//  log.Printf("launch %v", p.Redacted())
+func (p *Provider) Redacted() url.Values {
+	rp := DefaultRedactionPolicy
+	if p.Redaction != nil {
+		rp = *p.Redaction
+	}
+	v := url.Values{}
+	for k, vs := range p.Params() {
+		if rp.masks(k) {
+			v[k] = []string{RedactedValue}
+			continue
+		}
+		v[k] = append([]string(nil), vs...)
+	}
+	return v
+}
@@ -0,0 +1,35 @@
+package lti
+
+import "testing"
+
+func TestRedacted(t *testing.T) {
+	p := NewProvider("asdf", "http://localhost")
+	p.SetParams(GenerateForm())
+	p.Add("oauth_signature", "sig")
+	p.Add("custom_email", "user@school.edu")
+
+	v := p.Redacted()
+	for _, k := range []string{"oauth_signature", "lis_person_name_full",
+		"lis_person_contact_email_primary", "lis_result_sourcedid", "custom_email"} {
+		if v.Get(k) != RedactedValue {
+			t.Errorf("%s should be redacted, got %s", k, v.Get(k))
+		}
+	}
+	if v.Get("context_id") != "456434513" {
+		t.Errorf("context_id should not be redacted")
+	}
+	if p.Get("lis_person_name_full") != "Jane Q. Public" {
+		t.Errorf("Params should not be modified")
+	}
+	for _, k := range PIIFields() {
+		if !DefaultRedactionPolicy.masks(k) {
+			t.Errorf("%s should be masked by DefaultRedactionPolicy", k)
+		}
+	}
+
+	p.Redaction = &RedactionPolicy{Fields: []string{"context_id"}}
+	v = p.Redacted()
+	if v.Get("context_id") != RedactedValue || v.Get("lis_person_name_full") != "Jane Q. Public" {
+		t.Errorf("Custom policy not applied %v", v)
+	}
+}
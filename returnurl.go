@@ -0,0 +1,52 @@
+package lti
+
+import (
+	"errors"
+	"net/url"
+)
+
+// ErrNoReturnURL is returned by ReturnURL when the launch has no
+// launch_presentation_return_url.
+var ErrNoReturnURL = errors.New("no launch_presentation_return_url")
+
+// ReturnMessage holds the messages sent back to the consumer when
+// the user returns to it. Msg and ErrorMsg are shown to the user,
+// Log and ErrorLog are only logged.
+type ReturnMessage struct {
+	Msg      string
+	ErrorMsg string
+	Log      string
+	ErrorLog string
+}
+
+// ReturnURL returns the launch_presentation_return_url of the launch
+// with the messages of m added as the lti_msg, lti_errormsg, lti_log
+// and lti_errorlog params, to redirect the user back to the consumer:
+//
+//  u, err := p.ReturnURL(lti.ReturnMessage{Msg: "Quiz submitted"})
+//  if err == nil {
+//    http.Redirect(w, r, u, http.StatusFound)
+//  }
+func (p *Provider) ReturnURL(m ReturnMessage) (string, error) {
+	ru := p.Get("launch_presentation_return_url")
+	if ru == "" {
+		return "", ErrNoReturnURL
+	}
+	u, err := url.Parse(ru)
+	if err != nil {
+		return "", err
+	}
+	q := u.Query()
+	for k, v := range map[string]string{
+		"lti_msg":      m.Msg,
+		"lti_errormsg": m.ErrorMsg,
+		"lti_log":      m.Log,
+		"lti_errorlog": m.ErrorLog,
+	} {
+		if v != "" {
+			q.Set(k, v)
+		}
+	}
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
@@ -0,0 +1,34 @@
+package lti
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestReturnURL(t *testing.T) {
+	p := NewProvider("secret", "http://localhost")
+	if _, err := p.ReturnURL(ReturnMessage{}); !errors.Is(err, ErrNoReturnURL) {
+		t.Errorf("Expected ErrNoReturnURL, got %v", err)
+	}
+
+	p.Add("launch_presentation_return_url", "https://lms.example.com/return?launch=1")
+	tests := []struct {
+		m        ReturnMessage
+		expected string
+	}{
+		{ReturnMessage{}, "https://lms.example.com/return?launch=1"},
+		{ReturnMessage{Msg: "Quiz submitted"},
+			"https://lms.example.com/return?launch=1&lti_msg=Quiz+submitted"},
+		{ReturnMessage{ErrorMsg: "Try again", Log: "a&b", ErrorLog: "e"},
+			"https://lms.example.com/return?launch=1&lti_errorlog=e&lti_errormsg=Try+again&lti_log=a%26b"},
+	}
+	for _, tt := range tests {
+		u, err := p.ReturnURL(tt.m)
+		if err != nil {
+			t.Fatalf("Error building return URL %s", err)
+		}
+		if u != tt.expected {
+			t.Errorf("Expected %s, got %s", tt.expected, u)
+		}
+	}
+}
@@ -0,0 +1,198 @@
+package lti
+
+import "strings"
+
+// RoleScope tells where a role applies: the context (course) the
+// launch comes from, the institution, or the whole system.
+type RoleScope int
+
+// Role scopes, as defined by the LIS vocabulary.
+const (
+	ContextRole RoleScope = iota
+	InstitutionRole
+	SystemRole
+)
+
+// URN prefixes for each role scope.
+const (
+	contextRolePrefix     = "urn:lti:role:ims/lis/"
+	institutionRolePrefix = "urn:lti:instrole:ims/lis/"
+	systemRolePrefix      = "urn:lti:sysrole:ims/lis/"
+)
+
+// Role is a parsed LIS role. Name holds the role without the URN
+// prefix (Instructor), and Sub the sub role if any, so
+// urn:lti:role:ims/lis/Instructor/GuestInstructor is parsed as
+// {ContextRole, "Instructor", "GuestInstructor"}.
+type Role struct {
+	Scope RoleScope
+	Name  string
+	Sub   string
+}
+
+// String returns the full URN of the role.
+func (r Role) String() string {
+	var prefix string
+	switch r.Scope {
+	case InstitutionRole:
+		prefix = institutionRolePrefix
+	case SystemRole:
+		prefix = systemRolePrefix
+	default:
+		prefix = contextRolePrefix
+	}
+	if r.Sub != "" {
+		return prefix + r.Name + "/" + r.Sub
+	}
+	return prefix + r.Name
+}
+
+// lisRoles holds the LIS role names by lowercased name, used to
+// normalize the casing LMSes send.
+var lisRoles = map[string]string{}
+
+func init() {
+	for _, n := range []string{
+		// context
+		"Learner", "Instructor", "ContentDeveloper", "Member",
+		"Manager", "Mentor", "Administrator", "TeachingAssistant",
+		// institution
+		"Student", "Faculty", "Staff", "Alumni", "ProspectiveStudent",
+		"Guest", "Other", "Observer", "None",
+		// system
+		"SysAdmin", "SysSupport", "Creator", "AccountAdmin", "User",
+	} {
+		lisRoles[strings.ToLower(n)] = n
+	}
+}
+
+// ParseRole parses a role, either in its short form (Instructor)
+// or as a full URN. Short forms are context roles, as the LTI spec
+// says.
+func ParseRole(s string) Role {
+	s = strings.TrimSpace(s)
+	r := Role{Scope: ContextRole}
+	lower := strings.ToLower(s)
+	switch {
+	case strings.HasPrefix(lower, contextRolePrefix):
+		s = s[len(contextRolePrefix):]
+	case strings.HasPrefix(lower, institutionRolePrefix):
+		r.Scope = InstitutionRole
+		s = s[len(institutionRolePrefix):]
+	case strings.HasPrefix(lower, systemRolePrefix):
+		r.Scope = SystemRole
+		s = s[len(systemRolePrefix):]
+	}
+	if i := strings.Index(s, "/"); i >= 0 {
+		s, r.Sub = s[:i], s[i+1:]
+	}
+	r.Name = normalizeRoleName(s)
+	r.Sub = normalizeRoleName(r.Sub)
+	return r
+}
+
+// ParseRoles parses a comma separated list of roles, as sent in
+// the roles param.
+func ParseRoles(s string) []Role {
+	var roles []Role
+	for _, r := range strings.Split(s, ",") {
+		if strings.TrimSpace(r) == "" {
+			continue
+		}
+		roles = append(roles, ParseRole(r))
+	}
+	return roles
+}
+
+func normalizeRoleName(n string) string {
+	if c, ok := lisRoles[strings.ToLower(n)]; ok {
+		return c
+	}
+	return n
+}
+
+// matches reports if r satisfies the role asked. A short role
+// matches any scope, while a URN must match the scope, and the
+// sub role when it has one.
+func (r Role) matches(asked string) bool {
+	a := ParseRole(asked)
+	if r.Name != a.Name || (a.Sub != "" && r.Sub != a.Sub) {
+		return false
+	}
+	return !strings.Contains(asked, ":") || r.Scope == a.Scope
+}
+
+// hasAny reports if the launch has any of the roles given.
+func (p *Provider) hasAny(roles ...string) bool {
+	for _, r := range roles {
+		if p.HasRole(r) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsInstructor reports if the launch comes from an instructor,
+// including Instructor sub roles like TeachingAssistant.
+func (p *Provider) IsInstructor() bool {
+	return p.HasRole("Instructor")
+}
+
+// IsLearner reports if the launch comes from a learner.
+func (p *Provider) IsLearner() bool {
+	return p.HasRole("Learner")
+}
+
+// IsAdministrator reports if the launch comes from an administrator
+// of the context, the institution or the system.
+func (p *Provider) IsAdministrator() bool {
+	return p.hasAny("Administrator", systemRolePrefix+"SysAdmin")
+}
+
+// IsTeachingAssistant reports if the launch comes from a teaching
+// assistant, either the TeachingAssistant role or any of the
+// TeachingAssistant sub roles of Instructor.
+func (p *Provider) IsTeachingAssistant() bool {
+	if p.HasRole("TeachingAssistant") {
+		return true
+	}
+	for _, r := range ParseRoles(p.Get("roles")) {
+		if r.Name == "Instructor" && strings.HasPrefix(r.Sub, "TeachingAssistant") {
+			return true
+		}
+	}
+	return false
+}
+
+// IsContentDeveloper reports if the launch comes from a content
+// developer.
+func (p *Provider) IsContentDeveloper() bool {
+	return p.HasRole("ContentDeveloper")
+}
+
+// rolesIn returns the roles of the launch with the given scope.
+func (p *Provider) rolesIn(scope RoleScope) []Role {
+	var roles []Role
+	for _, r := range ParseRoles(p.Get("roles")) {
+		if r.Scope == scope {
+			roles = append(roles, r)
+		}
+	}
+	return roles
+}
+
+// ContextRoles returns the roles the user has in the context of
+// the launch, short roles included.
+func (p *Provider) ContextRoles() []Role {
+	return p.rolesIn(ContextRole)
+}
+
+// InstitutionRoles returns the urn:lti:instrole roles of the launch.
+func (p *Provider) InstitutionRoles() []Role {
+	return p.rolesIn(InstitutionRole)
+}
+
+// SystemRoles returns the urn:lti:sysrole roles of the launch.
+func (p *Provider) SystemRoles() []Role {
+	return p.rolesIn(SystemRole)
+}
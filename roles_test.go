@@ -0,0 +1,96 @@
+package lti
+
+import "testing"
+
+func TestParseRole(t *testing.T) {
+	tests := []struct {
+		in   string
+		role Role
+	}{
+		{"Instructor", Role{ContextRole, "Instructor", ""}},
+		{" learner", Role{ContextRole, "Learner", ""}},
+		{"urn:lti:role:ims/lis/Instructor", Role{ContextRole, "Instructor", ""}},
+		{"urn:lti:role:ims/lis/Instructor/GuestInstructor", Role{ContextRole, "Instructor", "GuestInstructor"}},
+		{"urn:lti:instrole:ims/lis/Administrator", Role{InstitutionRole, "Administrator", ""}},
+		{"urn:lti:sysrole:ims/lis/SysAdmin", Role{SystemRole, "SysAdmin", ""}},
+	}
+	for _, tt := range tests {
+		if r := ParseRole(tt.in); r != tt.role {
+			t.Errorf("ParseRole(%q) = %#v, expected %#v", tt.in, r, tt.role)
+		}
+	}
+
+	r := ParseRole("urn:lti:role:ims/lis/Instructor/GuestInstructor")
+	if r.String() != "urn:lti:role:ims/lis/Instructor/GuestInstructor" {
+		t.Errorf("Wrong role URN %s", r)
+	}
+}
+
+func TestHasRoleURN(t *testing.T) {
+	p := NewProvider("asdf", "http://localhost")
+	p.Add("roles", "urn:lti:role:ims/lis/Instructor,urn:lti:instrole:ims/lis/Administrator")
+
+	for _, r := range []string{
+		"Instructor",
+		"Administrator",
+		"urn:lti:role:ims/lis/Instructor",
+		"urn:lti:instrole:ims/lis/Administrator",
+	} {
+		if !p.HasRole(r) {
+			t.Errorf("Provider should have role %s", r)
+		}
+	}
+	for _, r := range []string{
+		"Learner",
+		"urn:lti:role:ims/lis/Administrator",
+		"urn:lti:role:ims/lis/Instructor/GuestInstructor",
+	} {
+		if p.HasRole(r) {
+			t.Errorf("Provider should not have role %s", r)
+		}
+	}
+}
+
+func TestRolePredicates(t *testing.T) {
+	p := NewProvider("asdf", "http://localhost")
+	p.Add("roles", "urn:lti:role:ims/lis/Instructor/TeachingAssistantSection,urn:lti:sysrole:ims/lis/SysAdmin")
+
+	if !p.IsInstructor() {
+		t.Error("Should be an instructor")
+	}
+	if !p.IsTeachingAssistant() {
+		t.Error("Should be a teaching assistant")
+	}
+	if !p.IsAdministrator() {
+		t.Error("Should be an administrator")
+	}
+	if p.IsLearner() || p.IsContentDeveloper() {
+		t.Error("Should not be a learner nor a content developer")
+	}
+
+	p.Add("roles", "Learner,ContentDeveloper")
+	if !p.IsLearner() || !p.IsContentDeveloper() {
+		t.Error("Should be a learner and a content developer")
+	}
+	if p.IsInstructor() || p.IsTeachingAssistant() || p.IsAdministrator() {
+		t.Error("Should only be a learner and a content developer")
+	}
+}
+
+func TestRolesByScope(t *testing.T) {
+	p := NewProvider("asdf", "http://localhost")
+	p.Add("roles", "Learner,urn:lti:role:ims/lis/Mentor,urn:lti:instrole:ims/lis/Student,urn:lti:sysrole:ims/lis/User")
+
+	ctx := p.ContextRoles()
+	if len(ctx) != 2 || ctx[0].Name != "Learner" || ctx[1].Name != "Mentor" {
+		t.Errorf("Wrong context roles %v", ctx)
+	}
+	inst := p.InstitutionRoles()
+	if len(inst) != 1 || inst[0].Name != "Student" {
+		t.Errorf("Wrong institution roles %v", inst)
+	}
+	sys := p.SystemRoles()
+	if len(sys) != 1 || sys[0].Name != "User" {
+		t.Errorf("Wrong system roles %v", sys)
+	}
+}
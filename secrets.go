@@ -0,0 +1,118 @@
+package lti
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/jordic/lti/oauth"
+)
+
+// SecretStore returns the secrets of the consumers, so a Provider
+// can verify the launches of several consumers, and a consumer can
+// have several secrets while they are rotated.
+type SecretStore interface {
+	// Secrets returns the secrets of the consumer key, or none if
+	// the key is unknown.
+	Secrets(consumerKey string) ([]string, error)
+}
+
+// StaticSecrets is a SecretStore holding the secrets by consumer
+// key:
+//
+//  p.Secrets = lti.StaticSecrets{
+//    "12345": {"new-secret", "old-secret"},
+//  }
+type StaticSecrets map[string][]string
+
+// Secrets returns the secrets of the consumer key.
+func (s StaticSecrets) Secrets(consumerKey string) ([]string, error) {
+	return s[consumerKey], nil
+}
+
+// secretVerifiers builds the verifier of a secret by signature
+// method, for the consumers of Secrets.
+var secretVerifiers = map[string]func(secret string) oauth.OauthVerifier{
+	oauth.HMACSHA1: func(s string) oauth.OauthVerifier {
+		return oauth.GetHMACSigner(s, "")
+	},
+	oauth.HMACSHA256: func(s string) oauth.OauthVerifier {
+		return oauth.GetHMAC256Signer(s, "")
+	},
+	oauth.PLAINTEXT: func(s string) oauth.OauthVerifier {
+		return oauth.GetPlaintextSigner(s, "")
+	},
+}
+
+// plaintextVerifiers returns a PLAINTEXT verifier by secret of the
+// consumer key, only for requests received over https, see
+// TrustProxy.
+func (p *Provider) plaintextVerifiers(r *http.Request, consumerKey string) ([]oauth.OauthVerifier, error) {
+	if !oauth.IsHTTPS(r, p.TrustProxy) {
+		return nil, fmt.Errorf("%w PLAINTEXT, only accepted over https", ErrInvalidSignatureMethod)
+	}
+	if p.Secrets == nil {
+		return []oauth.OauthVerifier{oauth.GetPlaintextSigner(p.Secret, "")}, nil
+	}
+	return p.secretVerifiers(consumerKey, oauth.PLAINTEXT)
+}
+
+// verifiers returns the verifiers of the launches of the consumer
+// key: the one of p if the key is ConsumerKey, or one by secret of
+// the key, with the signature method of p, when Secrets is set.
+func (p *Provider) verifiers(consumerKey string) ([]oauth.OauthVerifier, error) {
+	if p.Secrets == nil {
+		if consumerKey != p.ConsumerKey {
+			return nil, nil
+		}
+		return []oauth.OauthVerifier{p.verifier()}, nil
+	}
+	return p.secretVerifiers(consumerKey, p.verifier().GetMethod())
+}
+
+// secretVerifiers returns a verifier by secret of the consumer key
+// in Secrets, with the signature method, or HMAC-SHA1 for the
+// methods that don't sign with secrets.
+func (p *Provider) secretVerifiers(consumerKey, method string) ([]oauth.OauthVerifier, error) {
+	secrets, err := p.Secrets.Secrets(consumerKey)
+	if err != nil {
+		return nil, err
+	}
+	newVerifier, ok := secretVerifiers[method]
+	if !ok {
+		newVerifier = secretVerifiers[oauth.HMACSHA1]
+	}
+	var verifiers []oauth.OauthVerifier
+	for _, s := range secrets {
+		verifiers = append(verifiers, newVerifier(s))
+	}
+	return verifiers, nil
+}
+
+// verifier returns the Verifier of p, or its Signer, when it can
+// verify.
+func (p *Provider) verifier() oauth.OauthVerifier {
+	if p.Verifier != nil {
+		return p.Verifier
+	}
+	if v, ok := p.Signer.(oauth.OauthVerifier); ok {
+		return v
+	}
+	return signerVerifier{p.Signer}
+}
+
+// signerVerifier verifies by signing, for the signers that can't
+// verify.
+type signerVerifier struct {
+	oauth.OauthSigner
+}
+
+func (s signerVerifier) Verify(baseString, signature string) error {
+	sig, err := s.GetSignature(baseString)
+	if err != nil {
+		return err
+	}
+	if sig != signature {
+		return oauth.ErrSignatureMismatch
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package lti
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/jordic/lti/oauth"
+)
+
+type failingSecrets struct{}
+
+func (failingSecrets) Secrets(string) ([]string, error) {
+	return nil, errors.New("store down")
+}
+
+func TestSecretStore(t *testing.T) {
+	launch := func(key, secret string) *http.Request {
+		c := NewProvider(secret, "http://urltest.com/", WithConsumerKey(key))
+		c.Add("resource_link_id", "1")
+		c.Sign()
+		return &http.Request{Method: "POST", Form: c.Params()}
+	}
+
+	p := NewProvider("", "http://urltest.com/", WithSecretStore(StaticSecrets{
+		"12345": {"new", "old"},
+		"other": {"third"},
+	}))
+	for _, l := range [][2]string{{"12345", "new"}, {"12345", "old"}, {"other", "third"}} {
+		if ok, err := p.IsValid(launch(l[0], l[1])); !ok {
+			t.Errorf("Launch of %s signed with %s should be valid, got %s", l[0], l[1], err)
+		}
+	}
+
+	if _, err := p.IsValid(launch("12345", "third")); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("Launch signed with other secret should fail, got %v", err)
+	}
+	if _, err := p.IsValid(launch("unknown", "new")); !errors.Is(err, ErrInvalidConsumerKey) {
+		t.Errorf("Launch of unknown key should fail, got %v", err)
+	}
+
+	p.Secrets = failingSecrets{}
+	if ok, err := p.IsValid(launch("12345", "new")); ok || err.Error() != "store down" {
+		t.Errorf("Store errors should be returned, got %v", err)
+	}
+}
+
+func TestSecretStoreSHA256(t *testing.T) {
+	c := NewProvider("new", "http://urltest.com/", WithConsumerKey("12345"),
+		WithSigner(oauth.GetHMAC256Signer("new", "")))
+	c.Add("resource_link_id", "1")
+	c.Sign()
+
+	p := NewProvider("", "http://urltest.com/",
+		WithSigner(oauth.GetHMAC256Signer("", "")),
+		WithSecretStore(StaticSecrets{"12345": {"old", "new"}}))
+	if ok, err := p.IsValid(&http.Request{Method: "POST", Form: c.Params()}); !ok {
+		t.Errorf("HMAC-SHA256 launch of a SecretStore consumer should be valid, got %s", err)
+	}
+}
@@ -0,0 +1,85 @@
+package lti
+
+import (
+	"bytes"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/jordic/lti/oauth"
+)
+
+// SignRequest signs an outgoing request with the credentials of the
+// provider, over its method, URL, and the params collected by
+// oauth.CollectParameters, so the request doesn't need to be kept
+// in sync with the provider URL and Method. Requests with a form
+// body get the oauth params added to the form, any other request
+// gets them in an Authorization header, signing their body, if any,
+// with oauth_body_hash, hashed as the method of the Signer says.
+//
+// The params of the provider are not used nor modified.
+func (p *Provider) SignRequest(r *http.Request) error {
+	var body []byte
+	if r.Body != nil {
+		var err error
+		body, err = ioutil.ReadAll(r.Body)
+		r.Body.Close()
+		if err != nil {
+			return err
+		}
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+	ct, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	isForm := ct == "application/x-www-form-urlencoded"
+
+	params, err := oauth.CollectParameters(r)
+	if err != nil {
+		return err
+	}
+
+	n, err := p.nonce()
+	if err != nil {
+		return err
+	}
+	oauthParams := url.Values{}
+	oauthParams.Set("oauth_version", oAuthVersion)
+	oauthParams.Set("oauth_timestamp", strconv.FormatInt(p.now().Unix(), 10))
+	oauthParams.Set("oauth_nonce", n)
+	oauthParams.Set("oauth_signature_method", p.Signer.GetMethod())
+	oauthParams.Set("oauth_consumer_key", p.ConsumerKey)
+	if !isForm && len(body) > 0 {
+		oauthParams.Set("oauth_body_hash", bodyHash(body, p.Signer.GetMethod()))
+	}
+
+	signed := oauth.ToValues(params)
+	for k, vs := range oauthParams {
+		signed[k] = vs
+	}
+	// the params collected already hold the query of the URL
+	sig, err := p.sign(p.Signer, signed, withoutQuery(r.URL.String()), r.Method)
+	if err != nil {
+		return err
+	}
+	oauthParams.Set("oauth_signature", sig)
+
+	if isForm {
+		if len(body) > 0 {
+			body = append(body, '&')
+		}
+		body = append(body, oauthParams.Encode()...)
+	} else {
+		r.Header.Set("Authorization", authHeader(oauthParams))
+	}
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+	r.ContentLength = int64(len(body))
+	p.signHook(p.ConsumerKey)
+	return nil
+}
+
+// authHeader returns the OAuth Authorization header with params,
+// sorted by name.
+func authHeader(params url.Values) string {
+	return oauth.FormatAuthorizationHeader("", oauth.KVsFromValues(params))
+}
@@ -0,0 +1,102 @@
+package lti
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/jordic/lti/oauth"
+)
+
+func TestSignRequest(t *testing.T) {
+	c := NewProvider("asdf", "", WithConsumerKey("12345"))
+	p := NewProvider("asdf", "http://urltest.com/launch?a=1", WithConsumerKey("12345"))
+
+	r, _ := http.NewRequest("POST", "http://urltest.com/launch?a=1",
+		strings.NewReader("resource_link_id=1&roles=Learner"))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if err := c.SignRequest(r); err != nil {
+		t.Fatalf("Error signing request %s", err)
+	}
+	if r.Header.Get("Authorization") != "" {
+		t.Error("Form requests should be signed in the form")
+	}
+	if ok, err := p.IsValid(r); !ok {
+		t.Errorf("Signed form request should be valid, got %s", err)
+	}
+	if !c.Empty("oauth_signature") {
+		t.Error("Provider params should not be modified")
+	}
+
+	p.URL = "http://urltest.com/outcomes"
+	r, _ = http.NewRequest("POST", "http://urltest.com/outcomes",
+		strings.NewReader("<imsx_POXEnvelopeRequest/>"))
+	r.Header.Set("Content-Type", "application/xml")
+	if err := c.SignRequest(r); err != nil {
+		t.Fatalf("Error signing request %s", err)
+	}
+	if !strings.Contains(r.Header.Get("Authorization"), "oauth_body_hash") {
+		t.Errorf("XML requests should be signed in the header with a body hash, got %s",
+			r.Header.Get("Authorization"))
+	}
+	if ok, err := p.IsValid(r); !ok {
+		t.Errorf("Signed XML request should be valid, got %s", err)
+	}
+}
+
+func TestAuthHeaderOrder(t *testing.T) {
+	params := url.Values{
+		"oauth_version":   {"1.0"},
+		"oauth_nonce":     {"n"},
+		"oauth_signature": {"a+b="},
+		"oauth_body_hash": {"h"},
+	}
+	expected := `OAuth oauth_body_hash="h", oauth_nonce="n", oauth_signature="a%2Bb%3D", oauth_version="1.0"`
+	for i := 0; i < 10; i++ {
+		if h := authHeader(params); h != expected {
+			t.Fatalf("Unexpected header %s, expected %s", h, expected)
+		}
+	}
+}
+
+func TestSignRequestSHA256BodyHash(t *testing.T) {
+	c := NewProvider("asdf", "", WithConsumerKey("12345"),
+		WithSigner(oauth.GetHMAC256Signer("asdf", "")))
+	r, _ := http.NewRequest("POST", "http://urltest.com/outcomes",
+		strings.NewReader("<imsx_POXEnvelopeRequest/>"))
+	r.Header.Set("Content-Type", "application/xml")
+	if err := c.SignRequest(r); err != nil {
+		t.Fatalf("Error signing request %s", err)
+	}
+	err := oauth.VerifyRequest(r, func(key string) (string, string, error) {
+		return "asdf", "", nil
+	})
+	if err != nil {
+		t.Errorf("HMAC-SHA256 request should be verified by the oauth package, got %s", err)
+	}
+}
+
+func TestSignRequestDuplicatedParam(t *testing.T) {
+	c := NewProvider("asdf", "", WithConsumerKey("12345"))
+	p := NewProvider("asdf", "http://urltest.com/launch", WithConsumerKey("12345"))
+
+	r, _ := http.NewRequest("POST", "http://urltest.com/launch?a=1&b=2",
+		strings.NewReader("a=3&resource_link_id=1"))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if err := c.SignRequest(r); err != nil {
+		t.Fatalf("Error signing request %s", err)
+	}
+	err := oauth.VerifyRequest(r, func(key string) (string, string, error) {
+		return "asdf", "", nil
+	})
+	if err != nil {
+		t.Errorf("Param in query and body should be verified by the oauth package, got %s", err)
+	}
+	if ok, err := p.IsValid(r); !ok {
+		t.Errorf("Param in query and body should be valid, got %s", err)
+	}
+	if a := p.Params()["a"]; len(a) != 2 {
+		t.Errorf("Both values should be kept, got %v", a)
+	}
+}
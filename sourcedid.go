@@ -0,0 +1,61 @@
+package lti
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strings"
+)
+
+// ErrInvalidSourcedID is returned by DecodeSourcedID for values not
+// encoded by EncodeSourcedID, or tampered with.
+var ErrInvalidSourcedID = errors.New("invalid sourcedid")
+
+// EncodeSourcedID encodes parts, like the ids of the user and the
+// activity, into a lis_result_sourcedid value, to be decoded with
+// DecodeSourcedID when the result is sent:
+//
+//  sid := lti.EncodeSourcedID(key, userID, activityID)
+//
+// When key is given, the value is signed with an HMAC-SHA256 of it,
+// so tampered values are detected.
+func EncodeSourcedID(key []byte, parts ...string) string {
+	enc := make([]string, len(parts))
+	for i, p := range parts {
+		enc[i] = base64.RawURLEncoding.EncodeToString([]byte(p))
+	}
+	s := strings.Join(enc, ".")
+	if key != nil {
+		s += "." + sourcedIDMAC(key, s)
+	}
+	return s
+}
+
+// DecodeSourcedID returns the parts of a value encoded by
+// EncodeSourcedID, with the same key.
+func DecodeSourcedID(key []byte, s string) ([]string, error) {
+	enc := strings.Split(s, ".")
+	if key != nil {
+		i := strings.LastIndexByte(s, '.')
+		if i < 0 || !hmac.Equal([]byte(s[i+1:]), []byte(sourcedIDMAC(key, s[:i]))) {
+			return nil, ErrInvalidSourcedID
+		}
+		enc = enc[:len(enc)-1]
+	}
+	parts := make([]string, len(enc))
+	for i, e := range enc {
+		b, err := base64.RawURLEncoding.DecodeString(e)
+		if err != nil {
+			return nil, ErrInvalidSourcedID
+		}
+		parts[i] = string(b)
+	}
+	return parts, nil
+}
+
+func sourcedIDMAC(key []byte, s string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(s))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
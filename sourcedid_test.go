@@ -0,0 +1,33 @@
+package lti
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestSourcedID(t *testing.T) {
+	key := []byte("key")
+	parts := []string{"user:1", "quiz.2", ""}
+	for _, k := range [][]byte{nil, key} {
+		s := EncodeSourcedID(k, parts...)
+		got, err := DecodeSourcedID(k, s)
+		if err != nil {
+			t.Fatalf("Error decoding %s: %s", s, err)
+		}
+		if !reflect.DeepEqual(got, parts) {
+			t.Errorf("Expected %q, got %q", parts, got)
+		}
+	}
+
+	s := EncodeSourcedID(key, "user:1", "quiz")
+	tampered := EncodeSourcedID(nil, "user:2", "quiz") + s[len(EncodeSourcedID(nil, "user:1", "quiz")):]
+	for _, bad := range []string{tampered, "nodot", s + "x", EncodeSourcedID([]byte("other"), "user:1", "quiz")} {
+		if _, err := DecodeSourcedID(key, bad); !errors.Is(err, ErrInvalidSourcedID) {
+			t.Errorf("%s: expected ErrInvalidSourcedID, got %v", bad, err)
+		}
+	}
+	if _, err := DecodeSourcedID(nil, "not base64!"); !errors.Is(err, ErrInvalidSourcedID) {
+		t.Errorf("Expected ErrInvalidSourcedID, got %v", err)
+	}
+}
@@ -0,0 +1,85 @@
+package lti
+
+import "sync"
+
+// FailureKind is the category of a launch verification failure.
+type FailureKind string
+
+// Failure categories recorded in a FailureStore.
+const (
+	FailureBadSignature   FailureKind = "bad_signature"
+	FailureStaleTimestamp FailureKind = "stale_timestamp"
+	FailureReplay         FailureKind = "replay"
+	FailureUnknownKey     FailureKind = "unknown_key"
+)
+
+// FailureStore keeps counters of verification failures by consumer
+// key and category, so support staff can see which consumer is
+// misconfigured and how. Implementations backed by a database can
+// be plugged in to keep them across restarts.
+type FailureStore interface {
+	// RecordFailure increments the counter of kind for consumerKey.
+	RecordFailure(consumerKey string, kind FailureKind) error
+	// FailureCounts returns the counters of every consumer.
+	FailureCounts() (map[string]map[FailureKind]int64, error)
+}
+
+// UnknownConsumerKey is the key the failures of unknown consumers
+// are recorded with, as their oauth_consumer_key is not trusted.
+const UnknownConsumerKey = "(unknown)"
+
+// MaxFailureConsumers is the number of consumer keys, besides
+// UnknownConsumerKey, a MemoryFailureStore keeps counters for. The
+// failures of other keys are recorded with UnknownConsumerKey.
+const MaxFailureConsumers = 1000
+
+// MemoryFailureStore is a FailureStore kept in memory, for up to
+// MaxFailureConsumers keys.
+type MemoryFailureStore struct {
+	mu     sync.Mutex
+	counts map[string]map[FailureKind]int64
+}
+
+// NewMemoryFailureStore returns an empty MemoryFailureStore.
+func NewMemoryFailureStore() *MemoryFailureStore {
+	return &MemoryFailureStore{counts: map[string]map[FailureKind]int64{}}
+}
+
+// RecordFailure increments the counter of kind for consumerKey.
+func (s *MemoryFailureStore) RecordFailure(consumerKey string, kind FailureKind) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, ok := s.counts[consumerKey]
+	if !ok && len(s.counts) >= MaxFailureConsumers {
+		consumerKey = UnknownConsumerKey
+		c, ok = s.counts[consumerKey]
+	}
+	if !ok {
+		c = map[FailureKind]int64{}
+		s.counts[consumerKey] = c
+	}
+	c[kind]++
+	return nil
+}
+
+// FailureCounts returns a copy of the counters of every consumer.
+func (s *MemoryFailureStore) FailureCounts() (map[string]map[FailureKind]int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	res := make(map[string]map[FailureKind]int64, len(s.counts))
+	for key, c := range s.counts {
+		cc := make(map[FailureKind]int64, len(c))
+		for k, v := range c {
+			cc[k] = v
+		}
+		res[key] = cc
+	}
+	return res, nil
+}
+
+// recordFailure records a failure in p.Failures, if configured.
+func (p *Provider) recordFailure(consumerKey string, kind FailureKind) {
+	if p.Failures != nil {
+		p.Failures.RecordFailure(consumerKey, kind)
+	}
+}
@@ -0,0 +1,48 @@
+package lti
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+)
+
+func TestFailureStore(t *testing.T) {
+	s := NewMemoryFailureStore()
+	p := NewProvider("asdf", "http://urltest.com/",
+		WithConsumerKey("12345"),
+		WithFailureStore(s))
+
+	r := signedRequest(t, "http://urltest.com/")
+	r.Form.Set("resource_link_id", "tampered")
+	p.IsValid(r)
+
+	r = &http.Request{Method: "POST", Form: GenerateForm()}
+	r.Form.Set("oauth_consumer_key", "unknown")
+	p.IsValid(r)
+	p.IsValid(r)
+
+	counts, err := s.FailureCounts()
+	if err != nil {
+		t.Fatalf("Error reading counts %s", err)
+	}
+	if counts["12345"][FailureBadSignature] != 1 {
+		t.Errorf("Expected a bad signature failure, got %v", counts)
+	}
+	if counts[UnknownConsumerKey][FailureUnknownKey] != 2 || counts["unknown"] != nil {
+		t.Errorf("Expected two unknown key failures, got %v", counts)
+	}
+}
+
+func TestMemoryFailureStoreLimit(t *testing.T) {
+	s := NewMemoryFailureStore()
+	for i := 0; i < MaxFailureConsumers+10; i++ {
+		s.RecordFailure(strconv.Itoa(i), FailureBadSignature)
+	}
+	counts, _ := s.FailureCounts()
+	if len(counts) != MaxFailureConsumers+1 {
+		t.Errorf("Expected %d keys, got %d", MaxFailureConsumers+1, len(counts))
+	}
+	if counts[UnknownConsumerKey][FailureBadSignature] != 10 {
+		t.Errorf("Keys beyond the limit should be bucketed, got %v", counts[UnknownConsumerKey])
+	}
+}
@@ -0,0 +1,85 @@
+package lti
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Purger is implemented by the stores whose entries expire, like
+// MemoryNonceStore.
+type Purger interface {
+	// Purge removes the entries older than before, returning how
+	// many were removed.
+	Purge(ctx context.Context, before time.Time) (int, error)
+}
+
+// Sweeper purges the stores registered on it, each one with its
+// own retention window. It can run in the background, or be
+// triggered from a cron job with SweepNow:
+//
+//  s := lti.NewSweeper()
+//  s.Add("nonces", nonces, 10*time.Minute)
+//  go s.Run(ctx, time.Minute)
+type Sweeper struct {
+	mu     sync.Mutex
+	stores []sweepStore
+}
+
+type sweepStore struct {
+	name      string
+	purger    Purger
+	retention time.Duration
+}
+
+// NewSweeper returns a Sweeper without stores.
+func NewSweeper() *Sweeper {
+	return &Sweeper{}
+}
+
+// Add registers a store, whose entries will be purged once older
+// than retention.
+func (s *Sweeper) Add(name string, p Purger, retention time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stores = append(s.stores, sweepStore{name, p, retention})
+}
+
+// SweepNow purges every store, returning the number of entries
+// removed by store name. A failing store doesn't stop the others,
+// the first error is returned.
+func (s *Sweeper) SweepNow(ctx context.Context) (map[string]int, error) {
+	s.mu.Lock()
+	stores := append([]sweepStore(nil), s.stores...)
+	s.mu.Unlock()
+
+	var first error
+	removed := map[string]int{}
+	now := time.Now()
+	for _, st := range stores {
+		if err := ctx.Err(); err != nil {
+			return removed, err
+		}
+		n, err := st.purger.Purge(ctx, now.Add(-st.retention))
+		removed[st.name] += n
+		if err != nil && first == nil {
+			first = fmt.Errorf("purging %s: %w", st.name, err)
+		}
+	}
+	return removed, first
+}
+
+// Run sweeps the stores every interval until ctx is done.
+func (s *Sweeper) Run(ctx context.Context, interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			s.SweepNow(ctx)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package lti
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+type failingPurger struct{}
+
+func (failingPurger) Purge(ctx context.Context, before time.Time) (int, error) {
+	return 0, errors.New("db down")
+}
+
+func TestSweeper(t *testing.T) {
+	nonces := NewMemoryNonceStore(time.Hour)
+	now := time.Now()
+	nonces.Seen("key", "old", now.Add(-20*time.Minute))
+	nonces.Seen("key", "new", now)
+
+	ctx := context.Background()
+	launches := NewMemoryLaunchStore()
+	launches.SaveLaunch(ctx, &Launch{ID: "old", Created: now.Add(-2 * time.Hour)})
+	launches.SaveLaunch(ctx, &Launch{ID: "new", Created: now})
+
+	s := NewSweeper()
+	s.Add("failing", failingPurger{}, time.Minute)
+	s.Add("nonces", nonces, 10*time.Minute)
+	s.Add("launches", launches, time.Hour)
+
+	removed, err := s.SweepNow(ctx)
+	if err == nil {
+		t.Error("Error of the failing store should be returned")
+	}
+	if removed["nonces"] != 1 || removed["launches"] != 1 {
+		t.Errorf("Expected 1 nonce and 1 launch removed, got %v", removed)
+	}
+	if seen, _ := nonces.Seen("key", "new", now); !seen {
+		t.Error("Recent nonce should be kept")
+	}
+	if _, err := launches.LoadLaunch(ctx, "old"); !errors.Is(err, ErrLaunchNotFound) {
+		t.Errorf("Expired launch should be purged, got %v", err)
+	}
+	if _, err := launches.LoadLaunch(ctx, "new"); err != nil {
+		t.Errorf("Recent launch should be kept, got %v", err)
+	}
+}
@@ -0,0 +1,88 @@
+package lti
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+// Errors returned by IssueLaunchToken and VerifyLaunchToken.
+var (
+	ErrInvalidLaunchToken = errors.New("invalid launch token")
+	ErrExpiredLaunchToken = errors.New("expired launch token")
+	ErrNoTokenKey         = errors.New("no launch token key")
+)
+
+// LaunchToken is bound to a verified launch, so the requests that
+// follow it, like AJAX calls of the tool, can be authenticated.
+type LaunchToken struct {
+	// ID is unique for every token issued, it can be recorded to
+	// accept every token only once.
+	ID             string    `json:"id"`
+	ConsumerKey    string    `json:"consumer_key"`
+	UserID         string    `json:"user_id"`
+	ContextID      string    `json:"context_id,omitempty"`
+	ResourceLinkID string    `json:"resource_link_id"`
+	Expires        time.Time `json:"expires"`
+}
+
+// IssueLaunchToken returns a token bound to the launch held by p,
+// valid for ttl, signed with the TokenKey of p. It should be issued
+// after the launch is verified:
+//
+//  if ok, _ := p.IsValid(r); ok {
+//    token, err := p.IssueLaunchToken(time.Hour)
+//    ...
+//  }
+func (p *Provider) IssueLaunchToken(ttl time.Duration) (string, error) {
+	if len(p.TokenKey) == 0 {
+		return "", ErrNoTokenKey
+	}
+	b, err := json.Marshal(LaunchToken{
+		ID:             launchID(),
+		ConsumerKey:    p.Get("oauth_consumer_key"),
+		UserID:         p.Get("user_id"),
+		ContextID:      p.Get("context_id"),
+		ResourceLinkID: p.Get("resource_link_id"),
+		Expires:        time.Now().Add(ttl),
+	})
+	if err != nil {
+		return "", err
+	}
+	payload := base64.RawURLEncoding.EncodeToString(b)
+	return payload + "." + p.tokenMAC(payload), nil
+}
+
+// VerifyLaunchToken checks a token issued by IssueLaunchToken, with
+// the same TokenKey, returning the launch it is bound to.
+func (p *Provider) VerifyLaunchToken(token string) (*LaunchToken, error) {
+	if len(p.TokenKey) == 0 {
+		return nil, ErrNoTokenKey
+	}
+	i := strings.LastIndexByte(token, '.')
+	if i < 0 || !hmac.Equal([]byte(token[i+1:]), []byte(p.tokenMAC(token[:i]))) {
+		return nil, ErrInvalidLaunchToken
+	}
+	b, err := base64.RawURLEncoding.DecodeString(token[:i])
+	if err != nil {
+		return nil, ErrInvalidLaunchToken
+	}
+	var t LaunchToken
+	if err := json.Unmarshal(b, &t); err != nil {
+		return nil, ErrInvalidLaunchToken
+	}
+	if time.Now().After(t.Expires) {
+		return &t, ErrExpiredLaunchToken
+	}
+	return &t, nil
+}
+
+func (p *Provider) tokenMAC(payload string) string {
+	mac := hmac.New(sha256.New, p.TokenKey)
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,74 @@
+package lti
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestLaunchToken(t *testing.T) {
+	p := NewProvider("secret", "http://localhost", WithTokenKey([]byte("token key")))
+	p.SetParams(GenerateForm())
+
+	token, err := p.IssueLaunchToken(time.Hour)
+	if err != nil {
+		t.Fatalf("Error issuing token %s", err)
+	}
+	other, _ := p.IssueLaunchToken(time.Hour)
+	if other == token {
+		t.Error("Every token should be unique")
+	}
+
+	v := NewProvider("secret", "http://localhost", WithTokenKey([]byte("token key")))
+	lt, err := v.VerifyLaunchToken(token)
+	if err != nil {
+		t.Fatalf("Error verifying token %s", err)
+	}
+	if lt.ConsumerKey != "12345" || lt.UserID != "292832126" || lt.ContextID != "456434513" ||
+		lt.ResourceLinkID != "120988f929-274612" {
+		t.Errorf("Wrong token %#v", lt)
+	}
+
+	expired, _ := p.IssueLaunchToken(-time.Second)
+	if _, err := v.VerifyLaunchToken(expired); !errors.Is(err, ErrExpiredLaunchToken) {
+		t.Errorf("Expected ErrExpiredLaunchToken, got %v", err)
+	}
+
+	wrong := NewProvider("secret", "http://localhost", WithTokenKey([]byte("other key")))
+	for _, bad := range []string{token + "x", "x" + token, "nodot", ""} {
+		if _, err := v.VerifyLaunchToken(bad); !errors.Is(err, ErrInvalidLaunchToken) {
+			t.Errorf("%q: expected ErrInvalidLaunchToken, got %v", bad, err)
+		}
+	}
+	if _, err := wrong.VerifyLaunchToken(token); !errors.Is(err, ErrInvalidLaunchToken) {
+		t.Errorf("Token of other key should be invalid, got %v", err)
+	}
+}
+
+func TestLaunchTokenNoKey(t *testing.T) {
+	// A provider with only a SecretStore has no Secret, the token
+	// can't be keyed with it or anyone could forge one.
+	p := NewProvider("", "http://localhost",
+		WithSecretStore(StaticSecrets{"12345": {"secret"}}),
+		WithTokenKey(nil))
+	p.SetParams(GenerateForm())
+	if _, err := p.IssueLaunchToken(time.Hour); !errors.Is(err, ErrNoTokenKey) {
+		t.Errorf("Expected ErrNoTokenKey, got %v", err)
+	}
+
+	b, _ := json.Marshal(LaunchToken{ConsumerKey: "12345", UserID: "admin",
+		Expires: time.Now().Add(time.Hour)})
+	payload := base64.RawURLEncoding.EncodeToString(b)
+	for _, key := range []string{"", "lti-launch-token:"} {
+		mac := hmac.New(sha256.New, []byte(key))
+		mac.Write([]byte(payload))
+		forged := payload + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+		if _, err := p.VerifyLaunchToken(forged); !errors.Is(err, ErrNoTokenKey) {
+			t.Errorf("Forged token keyed with %q: expected ErrNoTokenKey, got %v", key, err)
+		}
+	}
+}
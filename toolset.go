@@ -0,0 +1,105 @@
+package lti
+
+import (
+	"net/http"
+	"net/url"
+	"sync"
+)
+
+// LaunchHandlerFunc handles a verified launch, p holds the params
+// of the launch.
+type LaunchHandlerFunc func(w http.ResponseWriter, r *http.Request, p *Provider)
+
+// ToolSet routes launches to several tools sharing the same
+// consumer registration. Each tool is mounted on its own path,
+// and can require the user to have some roles:
+//
+//  ts := lti.NewToolSet(lti.NewProvider("secret", "https://tools.example.com/"))
+//  ts.Handle("/quiz", quizHandler)
+//  ts.Handle("/grading", gradingHandler, "Instructor", "TeachingAssistant")
+//  http.ListenAndServe(":8080", ts)
+//
+// When the launches are verified by a Provider, the launch URL of
+// every tool is the URL of the provider with its path replaced by
+// the path of the tool. Any other LaunchVerifier must accept the
+// launch URLs of every tool itself.
+type ToolSet struct {
+	verifier LaunchVerifier
+
+	mu    sync.RWMutex
+	tools map[string]tool
+}
+
+type tool struct {
+	roles   []string
+	handler LaunchHandlerFunc
+}
+
+// NewToolSet returns a ToolSet verifying launches with v.
+func NewToolSet(v LaunchVerifier) *ToolSet {
+	return &ToolSet{
+		verifier: v,
+		tools:    map[string]tool{},
+	}
+}
+
+// Handle registers the handler of the tool mounted on path. When
+// roles are given, the user must have one of them to access it.
+func (ts *ToolSet) Handle(path string, h LaunchHandlerFunc, roles ...string) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	ts.tools[path] = tool{roles: roles, handler: h}
+}
+
+// invalidLaunch is the body of the 401 answers to failed launches.
+// The details of the failure are only reported to the Logger and
+// Hooks of the provider, as they can include the expected signature.
+const invalidLaunch = "invalid launch"
+
+// ServeHTTP verifies the launch and dispatches it to the tool
+// mounted on the request path. Failed launches are answered 401
+// with a generic body.
+func (ts *ToolSet) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ts.mu.RLock()
+	t, ok := ts.tools[r.URL.Path]
+	ts.mu.RUnlock()
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	p, err := ts.verify(r)
+	if err != nil {
+		http.Error(w, invalidLaunch, http.StatusUnauthorized)
+		return
+	}
+	if len(t.roles) > 0 && !p.hasAny(t.roles...) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+	t.handler(w, r, p)
+}
+
+// verify verifies r, with the URL of its tool when verified by a
+// Provider.
+func (ts *ToolSet) verify(r *http.Request) (*Provider, error) {
+	p, ok := ts.verifier.(*Provider)
+	if !ok {
+		return ts.verifier.Verify(r)
+	}
+	p = p.Clone()
+	p.URL = toolURL(p.URL, r.URL.Path)
+	_, err := p.IsValid(r)
+	return p, err
+}
+
+// toolURL replaces the path of base with path.
+func toolURL(base, path string) string {
+	u, err := url.Parse(base)
+	if err != nil {
+		return base
+	}
+	u.Path = path
+	u.RawPath = ""
+	return u.String()
+}
@@ -0,0 +1,60 @@
+package lti
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func launchRequest(t *testing.T, u, roles string) *http.Request {
+	c := NewProvider("asdf", u, WithConsumerKey("12345"))
+	c.Add("resource_link_id", "1").Add("roles", roles)
+	if _, err := c.Sign(); err != nil {
+		t.Fatalf("Error signing %s", err)
+	}
+	r, _ := http.NewRequest("POST", u, strings.NewReader(c.Params().Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return r
+}
+
+func TestToolSet(t *testing.T) {
+	ts := NewToolSet(NewProvider("asdf", "http://urltest.com/", WithConsumerKey("12345")))
+	ts.Handle("/quiz", func(w http.ResponseWriter, r *http.Request, p *Provider) {
+		w.Write([]byte("quiz " + p.Get("resource_link_id")))
+	})
+	ts.Handle("/grading", func(w http.ResponseWriter, r *http.Request, p *Provider) {
+		w.Write([]byte("grading"))
+	}, "Instructor")
+
+	tests := []struct {
+		url   string
+		roles string
+		code  int
+		body  string
+	}{
+		{"http://urltest.com/quiz", "Learner", http.StatusOK, "quiz 1"},
+		{"http://urltest.com/grading", "Instructor", http.StatusOK, "grading"},
+		{"http://urltest.com/grading", "Learner", http.StatusForbidden, "Forbidden"},
+		{"http://urltest.com/other", "Learner", http.StatusNotFound, "not found"},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		ts.ServeHTTP(w, launchRequest(t, tt.url, tt.roles))
+		if w.Code != tt.code || !strings.Contains(w.Body.String(), tt.body) {
+			t.Errorf("%s as %s: got %d %s", tt.url, tt.roles, w.Code, w.Body.String())
+		}
+	}
+
+	// signed for a tool, sent to another
+	w := httptest.NewRecorder()
+	r := launchRequest(t, "http://urltest.com/quiz", "Instructor")
+	r.URL.Path = "/grading"
+	ts.ServeHTTP(w, r)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Launch signed for another tool should fail, got %d", w.Code)
+	}
+	if body := strings.TrimSpace(w.Body.String()); body != invalidLaunch {
+		t.Errorf("Failed launch should get a generic body, got %s", body)
+	}
+}
@@ -0,0 +1,70 @@
+package lti
+
+import (
+	"net/url"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Warning is a problem found converting a stored launch.
+type Warning struct {
+	// Field is the param with the problem, if any.
+	Field   string
+	Message string
+}
+
+func (w Warning) String() string {
+	if w.Field == "" {
+		return w.Message
+	}
+	return w.Field + ": " + w.Message
+}
+
+// UpgradeRecord converts the params of a launch stored as raw
+// url.Values into a Launch, to migrate stored launches:
+//
+//  l, warnings := lti.UpgradeRecord(values)
+//  for _, w := range warnings {
+//    log.Printf("launch %s: %s", l.ID, w)
+//  }
+//  store.SaveLaunch(ctx, l)
+//
+// The launch gets a new ID, and its Created time is the
+// oauth_timestamp of the launch. The params that a launch would
+// need are reported as warnings, but the launch is converted anyway.
+func UpgradeRecord(record map[string][]string) (*Launch, []Warning) {
+	p := &Provider{}
+	p.SetParams(url.Values(record))
+	l := NewLaunch(p)
+	l.Created = time.Time{}
+
+	var warnings []Warning
+	for _, err := range p.ValidateLaunch() {
+		warnings = append(warnings, Warning{Message: err.Error()})
+	}
+	for _, f := range []string{"oauth_consumer_key", "user_id"} {
+		if p.Empty(f) {
+			warnings = append(warnings, Warning{Field: f, Message: "missing"})
+		}
+	}
+	var repeated []string
+	for k, vs := range record {
+		if len(vs) > 1 {
+			repeated = append(repeated, k)
+		}
+	}
+	sort.Strings(repeated)
+	for _, k := range repeated {
+		warnings = append(warnings, Warning{Field: k,
+			Message: "repeated, only the first value is used by the typed fields"})
+	}
+
+	if ts, err := strconv.ParseInt(p.Get("oauth_timestamp"), 10, 64); err == nil {
+		l.Created = time.Unix(ts, 0)
+	} else {
+		warnings = append(warnings, Warning{Field: "oauth_timestamp",
+			Message: "missing or invalid, Created is not set"})
+	}
+	return l, warnings
+}
@@ -0,0 +1,48 @@
+package lti
+
+import (
+	"testing"
+	"time"
+)
+
+func TestUpgradeRecord(t *testing.T) {
+	l, warnings := UpgradeRecord(GenerateForm())
+	if len(warnings) != 0 {
+		t.Errorf("Complete launch should not get warnings, got %v", warnings)
+	}
+	if l.ID == "" || l.UserID != "292832126" || l.ConsumerKey != "12345" {
+		t.Errorf("Wrong launch %#v", l)
+	}
+	if !l.Created.Equal(time.Unix(1348093590, 0)) {
+		t.Errorf("Created should be the launch timestamp, got %s", l.Created)
+	}
+
+	record := map[string][]string{
+		"lti_message_type": {LaunchMessageType},
+		"lti_version":      {"LTI-1p0"},
+		"resource_link_id": {"1"},
+		"roles":            {"Learner", "Instructor"},
+	}
+	l, warnings = UpgradeRecord(record)
+	expected := []string{
+		"oauth_consumer_key: missing",
+		"user_id: missing",
+		"roles: repeated, only the first value is used by the typed fields",
+		"oauth_timestamp: missing or invalid, Created is not set",
+	}
+	if len(warnings) != len(expected) {
+		t.Fatalf("Expected %d warnings, got %v", len(expected), warnings)
+	}
+	for i, w := range warnings {
+		if w.String() != expected[i] {
+			t.Errorf("Warning %d should be %q, got %q", i, expected[i], w)
+		}
+	}
+	if !l.Created.IsZero() || l.Roles != "Learner" {
+		t.Errorf("Wrong launch %#v", l)
+	}
+	record["roles"][0] = "changed"
+	if l.Params.Get("roles") != "Learner" {
+		t.Error("Launch params should not share the record")
+	}
+}
@@ -0,0 +1,83 @@
+package lti
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/jordic/lti/oauth"
+)
+
+// URLPolicy defines which differences between the registered
+// launch URL and the one the consumer signed are tolerated. The
+// URLs are always normalized as RFC 5849 says before signing, so
+// the case of the scheme and host, and the default ports, never
+// matter.
+type URLPolicy int
+
+const (
+	// URLExact requires the signed URL to be exactly Provider.URL.
+	URLExact URLPolicy = 0
+	// URLIgnoreTrailingSlash accepts the URL with or without
+	// a trailing slash in its path.
+	URLIgnoreTrailingSlash URLPolicy = 1 << iota
+)
+
+// launchURLs returns the URLs a launch can be signed with,
+// starting with the registered URL, followed by AltURLs and the
+// ones of LaunchURLs.
+func (p *Provider) launchURLs(r *http.Request) []string {
+	var urls []string
+	if p.URL != "" || !p.DetectURL {
+		urls = append(urls, p.URL)
+	}
+	for _, u := range p.AltURLs {
+		urls = appendURL(urls, u)
+	}
+	if p.LaunchURLs != nil {
+		for _, u := range p.LaunchURLs(r) {
+			urls = appendURL(urls, u)
+		}
+	}
+	if p.DetectURL {
+		urls = appendURL(urls, oauth.RequestURL(r, p.TrustProxy))
+	}
+	if p.URLPolicy&URLIgnoreTrailingSlash != 0 {
+		for _, u := range urls {
+			urls = appendURL(urls, toggleSlash(u))
+		}
+	}
+	return urls
+}
+
+func appendURL(urls []string, u string) []string {
+	for _, v := range urls {
+		if v == u {
+			return urls
+		}
+	}
+	return append(urls, u)
+}
+
+func toggleSlash(s string) string {
+	u, err := url.Parse(s)
+	if err != nil {
+		return s
+	}
+	if strings.HasSuffix(u.Path, "/") {
+		u.Path = strings.TrimSuffix(u.Path, "/")
+	} else {
+		u.Path += "/"
+	}
+	return u.String()
+}
+
+// withoutQuery returns the URL u without its query.
+func withoutQuery(u string) string {
+	pu, err := url.Parse(u)
+	if err != nil {
+		return u
+	}
+	pu.RawQuery = ""
+	return pu.String()
+}
@@ -0,0 +1,100 @@
+package lti
+
+import (
+	"net/http"
+	"testing"
+)
+
+func signedRequest(t *testing.T, u string) *http.Request {
+	p := NewProvider("asdf", u)
+	p.ConsumerKey = "12345"
+	p.Add("resource_link_id", "1086")
+	if _, err := p.Sign(); err != nil {
+		t.Fatalf("Error signing %s", err)
+	}
+	return &http.Request{Method: "POST", Form: p.Params()}
+}
+
+func TestURLPolicy(t *testing.T) {
+	tests := []struct {
+		registered string
+		signed     string
+		policy     URLPolicy
+		valid      bool
+	}{
+		{"http://urltest.com/launch", "http://urltest.com/launch", URLExact, true},
+		{"http://urltest.com/launch", "http://urltest.com/launch/", URLExact, false},
+		{"http://urltest.com/launch", "http://urltest.com/launch/", URLIgnoreTrailingSlash, true},
+		{"http://urltest.com/launch/", "http://urltest.com/launch", URLIgnoreTrailingSlash, true},
+		{"http://URLtest.com/launch", "http://urltest.com/launch", URLExact, true},
+		{"https://urltest.com:443/launch", "https://urltest.com/launch", URLExact, true},
+		{"HTTP://URLtest.com/launch", "http://urltest.com/launch", URLExact, true},
+		{"http://URLtest.com/launch", "http://urltest.com/launch/", URLExact, false},
+		{"http://URLtest.com/launch", "http://urltest.com/launch/", URLIgnoreTrailingSlash, true},
+	}
+	for _, tt := range tests {
+		p := NewProvider("asdf", tt.registered)
+		p.ConsumerKey = "12345"
+		p.URLPolicy = tt.policy
+		ok, err := p.IsValid(signedRequest(t, tt.signed))
+		if ok != tt.valid {
+			t.Errorf("%s signed as %s with policy %d: expected %v, got %v (%v)",
+				tt.registered, tt.signed, tt.policy, tt.valid, ok, err)
+		}
+	}
+}
+
+func TestDetectURL(t *testing.T) {
+	p := NewProvider("asdf", "", WithConsumerKey("12345"), WithDetectURL())
+
+	proxied := func() *http.Request {
+		r := launchRequest(t, "https://vanity.example.com/launch", "Learner")
+		r.URL.Scheme, r.URL.Host, r.Host = "", "", "backend:8080"
+		r.Header.Set("X-Forwarded-Proto", "https")
+		r.Header.Set("X-Forwarded-Host", "vanity.example.com, proxy.local")
+		return r
+	}
+	if ok, _ := p.IsValid(proxied()); ok {
+		t.Error("Forwarded headers should be ignored without TrustProxy")
+	}
+	p.TrustProxy = true
+	if ok, err := p.IsValid(proxied()); !ok {
+		t.Errorf("Launch behind proxy should be valid, got %s", err)
+	}
+	p.TrustProxy = false
+
+	r := launchRequest(t, "http://urltest.com/launch", "Learner")
+	if ok, err := p.IsValid(r); !ok {
+		t.Errorf("Launch should be valid, got %s", err)
+	}
+
+	p.DetectURL = false
+	r = launchRequest(t, "http://urltest.com/launch", "Learner")
+	if ok, _ := p.IsValid(r); ok {
+		t.Error("Launch should fail without URL detection")
+	}
+}
+
+func TestAltURLs(t *testing.T) {
+	p := NewProvider("asdf", "https://tool.example.com/launch", WithConsumerKey("12345"))
+	p.AltURLs = []string{"https://staging.example.com/launch"}
+	p.LaunchURLs = func(r *http.Request) []string {
+		return []string{"https://" + r.Host + "/launch"}
+	}
+
+	for _, u := range []string{
+		"https://tool.example.com/launch",
+		"https://staging.example.com/launch",
+		"https://cdn.example.net/launch",
+	} {
+		if ok, err := p.IsValid(launchRequest(t, u, "Learner")); !ok {
+			t.Errorf("Launch signed with %s should be valid, got %s", u, err)
+		}
+	}
+
+	r := launchRequest(t, "https://other.example.com/launch", "Learner")
+	r.Host = "cdn.example.net"
+	if ok, _ := p.IsValid(r); ok {
+		t.Error("Launch signed with an unknown URL should fail")
+	}
+}
@@ -0,0 +1,147 @@
+package lti
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// LaunchMessageType is the lti_message_type of a basic launch.
+const LaunchMessageType = "basic-lti-launch-request"
+
+// The lti_version values supported.
+const (
+	LTI1p0 = "LTI-1p0"
+	LTI1p2 = "LTI-1p2"
+)
+
+// supportedVersions holds the lti_version values accepted by
+// ValidateLaunch.
+var supportedVersions = []string{LTI1p0, LTI1p2}
+
+// ValidateLaunch checks that the params stored on the provider
+// describe a basic LTI launch. A valid signature does not make a
+// valid launch, so it is meant to be called after IsValid:
+//
+//  if ok, err := p.IsValid(r); ok {
+//    if errs := p.ValidateLaunch(); len(errs) > 0 {
+//      ...
+//    }
+//  }
+//
+// It returns the list of violations found, or nil when the
+// launch is correct. An lti_version other than LTI1p0 or LTI1p2 is
+// reported wrapping ErrUnsupportedLTIVersion, unless LenientVersion
+// is set. With StrictMode, the rest of the LTI 1.1 conformance
+// checks are run too.
+func (p *Provider) ValidateLaunch() []error {
+	var errs []error
+
+	switch mt := p.Get("lti_message_type"); mt {
+	case LaunchMessageType:
+	case "":
+		errs = append(errs, fmt.Errorf("missing lti_message_type"))
+	default:
+		errs = append(errs, fmt.Errorf("wrong lti_message_type %s", mt))
+	}
+
+	if v := p.Get("lti_version"); v == "" {
+		errs = append(errs, fmt.Errorf("missing lti_version"))
+	} else if !isSupportedVersion(v) && !p.LenientVersion {
+		errs = append(errs, fmt.Errorf("%w %s", ErrUnsupportedLTIVersion, v))
+	}
+
+	if p.Empty("resource_link_id") {
+		errs = append(errs, fmt.Errorf("missing resource_link_id"))
+	}
+	if p.StrictMode {
+		errs = append(errs, p.strictChecks()...)
+	}
+	return errs
+}
+
+// recommendedParams are the params a conformant consumer should
+// send on every launch.
+var recommendedParams = []string{
+	"user_id",
+	"roles",
+	"context_id",
+	"launch_presentation_return_url",
+	"tool_consumer_instance_guid",
+}
+
+// strictChecks runs the LTI 1.1 conformance checks not done by
+// default.
+func (p *Provider) strictChecks() []error {
+	var errs []error
+	if cb := p.Get("oauth_callback"); cb != "about:blank" {
+		errs = append(errs, fmt.Errorf("oauth_callback should be about:blank, got %q", cb))
+	}
+	params := p.Params()
+	keys := make([]string, 0, len(params))
+	for k := range params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if strings.HasPrefix(k, "oauth_") && len(params[k]) > 1 {
+			errs = append(errs, fmt.Errorf("duplicated %s", k))
+		}
+	}
+	return errs
+}
+
+// recommendedWarnings returns a Warning by recommended param missing.
+func (p *Provider) recommendedWarnings() []Warning {
+	var warnings []Warning
+	for _, k := range recommendedParams {
+		if p.Empty(k) {
+			warnings = append(warnings, Warning{Field: k, Message: "recommended param missing"})
+		}
+	}
+	return warnings
+}
+
+func isSupportedVersion(v string) bool {
+	for _, s := range supportedVersions {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidationResult holds the checks failed by a launch request.
+type ValidationResult struct {
+	// Errors holds an error by failed check, wrapping the Err*
+	// values of the package when it applies.
+	Errors []error
+	// Warnings holds the recommended params missing, only checked
+	// with StrictMode.
+	Warnings []Warning
+}
+
+// Valid reports if every check passed.
+func (v *ValidationResult) Valid() bool {
+	return len(v.Errors) == 0
+}
+
+// Validate verifies the launch request r as IsValid does, and the
+// required launch params as ValidateLaunch does, but instead of
+// stopping at the first problem it runs every check, returning all
+// the failures found, which makes integration problems easier to
+// debug:
+//
+//  res := p.Validate(r)
+//  for _, err := range res.Errors {
+//    log.Printf("launch check failed: %s", err)
+//  }
+func (p *Provider) Validate(r *http.Request) *ValidationResult {
+	res := &ValidationResult{Errors: p.verify(r, true)}
+	res.Errors = append(res.Errors, p.ValidateLaunch()...)
+	if p.StrictMode {
+		res.Warnings = p.recommendedWarnings()
+	}
+	return res
+}
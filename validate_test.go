@@ -0,0 +1,134 @@
+package lti
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestValidateLaunch(t *testing.T) {
+	p := NewProvider("secret", "http://localhost")
+	p.SetParams(GenerateForm())
+
+	if errs := p.ValidateLaunch(); len(errs) != 0 {
+		t.Errorf("Launch should be valid, got %v", errs)
+	}
+}
+
+func TestValidateLaunchViolations(t *testing.T) {
+	p := NewProvider("secret", "http://localhost")
+	p.Add("lti_message_type", "ContentItemSelectionRequest").
+		Add("lti_version", "LTI-2p0")
+
+	errs := p.ValidateLaunch()
+	if len(errs) != 3 {
+		t.Fatalf("Expected 3 violations, got %v", errs)
+	}
+	expected := []string{"lti_message_type", "lti_version", "resource_link_id"}
+	for i, e := range expected {
+		if !strings.Contains(errs[i].Error(), e) {
+			t.Errorf("Violation %d should be about %s, got %s", i, e, errs[i])
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	p := NewProvider("asdf", "http://urltest.com/", WithConsumerKey("12345"))
+	p.ClockSkew = DefaultClockSkew
+
+	c := NewProvider("asdf", "http://urltest.com/", WithConsumerKey("12345"))
+	c.SetParams(GenerateForm())
+	c.Params().Del("oauth_timestamp")
+	c.Params().Del("oauth_nonce")
+	c.Sign()
+	r := &http.Request{Method: "POST", Form: c.Params()}
+	if res := p.Validate(r); !res.Valid() {
+		t.Errorf("Launch should be valid, got %v", res.Errors)
+	}
+
+	c.Add("oauth_timestamp", "1348093590")
+	r = &http.Request{Method: "POST", Form: c.Params()}
+	r.Form.Set("oauth_consumer_key", "other")
+	r.Form.Del("resource_link_id")
+
+	res := p.Validate(r)
+	if res.Valid() || len(res.Errors) != 4 {
+		t.Fatalf("Expected 4 errors, got %v", res.Errors)
+	}
+	for i, e := range []error{ErrInvalidConsumerKey, ErrInvalidSignature, ErrExpiredTimestamp} {
+		if !errors.Is(res.Errors[i], e) {
+			t.Errorf("Error %d should be %s, got %s", i, e, res.Errors[i])
+		}
+	}
+	if !strings.Contains(res.Errors[3].Error(), "resource_link_id") {
+		t.Errorf("Missing resource_link_id should be reported, got %s", res.Errors[3])
+	}
+}
+
+func TestValidateLaunchVersion(t *testing.T) {
+	p := NewProvider("secret", "http://localhost")
+	p.SetParams(GenerateForm())
+
+	for v, ok := range map[string]bool{LTI1p0: true, LTI1p2: true, "LTI-2p0": false, "1.0": false} {
+		p.Add("lti_version", v)
+		errs := p.ValidateLaunch()
+		if ok != (len(errs) == 0) {
+			t.Errorf("Version %s: expected valid %v, got %v", v, ok, errs)
+		}
+		if !ok && (len(errs) != 1 || !errors.Is(errs[0], ErrUnsupportedLTIVersion)) {
+			t.Errorf("Version %s should fail with ErrUnsupportedLTIVersion, got %v", v, errs)
+		}
+	}
+
+	p.LenientVersion = true
+	if errs := p.ValidateLaunch(); len(errs) != 0 {
+		t.Errorf("Lenient mode should accept any version, got %v", errs)
+	}
+	p.Del("lti_version")
+	if errs := p.ValidateLaunch(); len(errs) != 1 {
+		t.Errorf("Lenient mode should still require a version, got %v", errs)
+	}
+}
+
+func TestValidateStrictMode(t *testing.T) {
+	p := NewProvider("asdf", "http://urltest.com/", WithConsumerKey("12345"), WithStrictMode())
+
+	c := NewProvider("asdf", "http://urltest.com/", WithConsumerKey("12345"))
+	c.SetParams(GenerateForm())
+	c.Params().Del("oauth_timestamp")
+	c.Params().Del("oauth_nonce")
+	c.Sign()
+	res := p.Validate(&http.Request{Method: "POST", Form: c.Params()})
+	if !res.Valid() || len(res.Warnings) != 0 {
+		t.Errorf("Conformant launch should be valid, got %v %v", res.Errors, res.Warnings)
+	}
+
+	c.Del("oauth_signature").
+		Del("context_id").
+		Add("oauth_callback", "http://evil.example.com/")
+	c.Params().Add("oauth_nonce", "other")
+	res = p.Validate(&http.Request{Method: "POST", Form: c.Params()})
+	var strict []string
+	for _, err := range res.Errors {
+		if !errors.Is(err, ErrInvalidSignature) {
+			strict = append(strict, err.Error())
+		}
+	}
+	expected := []string{
+		`oauth_callback should be about:blank, got "http://evil.example.com/"`,
+		"duplicated oauth_nonce",
+	}
+	if !reflect.DeepEqual(strict, expected) {
+		t.Errorf("Expected %q, got %q", expected, strict)
+	}
+	if len(res.Warnings) != 1 || res.Warnings[0].Field != "context_id" {
+		t.Errorf("Expected a context_id warning, got %v", res.Warnings)
+	}
+
+	p.StrictMode = false
+	if res := p.Validate(&http.Request{Method: "POST", Form: c.Params()}); len(res.Warnings) != 0 {
+		t.Errorf("Warnings should only be checked in strict mode, got %v", res.Warnings)
+	}
+}